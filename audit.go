@@ -0,0 +1,390 @@
+package rigel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// Operations recorded in the audit log
+const (
+	AuditAddSchema    = "add_schema"
+	AuditDeleteSchema = "delete_schema"
+	AuditCreateConfig = "create_config"
+	AuditDeleteConfig = "delete_config"
+	AuditSet          = "set"
+	AuditSetMany      = "set_many"
+	AuditDeleteKey    = "delete_key"
+	AuditRollback     = "rollback"
+	AuditMigrate      = "migrate_config"
+	AuditCloneConfig  = "clone_config"
+	AuditImportConfig = "import_config"
+	AuditSetParent    = "set_parent"
+)
+
+// Outcomes of an audited operation
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Sources of an audited operation, set on the context with WithSource
+const (
+	SourceCLI = "cli"
+	SourceAPI = "api"
+)
+
+// auditPrefix is where StorageAuditSink keeps the audit log. It is outside rigelPrefix so that it
+// cannot clash with an app name.
+const auditPrefix = "/remiges/rigel-audit/"
+
+// AuditRecord describes one administrative operation. Operations that change several config keys
+// produce one record per key.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor,omitempty"`  // Actor is the actor set on the context with WithActor
+	Source    string    `json:"source,omitempty"` // Source is the source set on the context with WithSource
+	Operation string    `json:"op"`
+	App       string    `json:"app"`
+	Module    string    `json:"module"`
+	Version   int       `json:"ver"`
+	Config    string    `json:"config,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	OldValue  string    `json:"old,omitempty"`
+	NewValue  string    `json:"new,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"` // Error is the reason the operation failed
+}
+
+// AuditSink receives the audit records of a Rigel client. Records are written after the operation has
+// completed, so a sink cannot stop an operation; it must deal with its own failures.
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// AuditReader is implemented by sinks that can read back the records they were given.
+type AuditReader interface {
+	Records(ctx context.Context, filter AuditFilter) ([]AuditRecord, error)
+}
+
+// AuditFilter selects audit records. Empty fields match any record.
+type AuditFilter struct {
+	App       string
+	Module    string
+	Version   int
+	Config    string
+	Key       string
+	Actor     string
+	Source    string
+	Operation string
+	Outcome   string
+	Since     time.Time // Since, if set, skips records made before it
+	Until     time.Time // Until, if set, skips records made after it
+	Limit     int       // Limit, if positive, keeps only the most recent records
+}
+
+// Match reports whether record is selected by the filter. Limit is not taken into account.
+func (f AuditFilter) Match(record AuditRecord) bool {
+	switch {
+	case f.App != "" && f.App != record.App,
+		f.Module != "" && f.Module != record.Module,
+		f.Version != 0 && f.Version != record.Version,
+		f.Config != "" && f.Config != record.Config,
+		f.Key != "" && f.Key != record.Key,
+		f.Actor != "" && f.Actor != record.Actor,
+		f.Source != "" && f.Source != record.Source,
+		f.Operation != "" && f.Operation != record.Operation,
+		f.Outcome != "" && f.Outcome != record.Outcome,
+		!f.Since.IsZero() && record.Time.Before(f.Since),
+		!f.Until.IsZero() && record.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// apply returns the records, which must be oldest first, selected by the filter.
+func (f AuditFilter) apply(records []AuditRecord) []AuditRecord {
+	selected := make([]AuditRecord, 0, len(records))
+	for _, record := range records {
+		if f.Match(record) {
+			selected = append(selected, record)
+		}
+	}
+	if f.Limit > 0 && len(selected) > f.Limit {
+		selected = selected[len(selected)-f.Limit:]
+	}
+	return selected
+}
+
+type sourceKey struct{}
+
+// WithSource returns a copy of ctx that records source, such as SourceCLI or SourceAPI, as where the
+// operations made with it come from.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFromContext returns the source set with WithSource, or an empty string if there is none.
+func SourceFromContext(ctx context.Context) string {
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
+
+// WithAuditSink sets the sink that receives an audit record for every schema and config change
+// made with the Rigel object, and returns the modified Rigel object.
+func (r *Rigel) WithAuditSink(sink AuditSink) *Rigel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditSink = sink
+	return r
+}
+
+// AuditSink returns the sink set with WithAuditSink, or nil if changes are not audited.
+func (r *Rigel) AuditSink() AuditSink {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.auditSink
+}
+
+// AuditLog returns the audit records selected by filter, oldest first.
+// It fails if the audit sink cannot read back its records.
+func (r *Rigel) AuditLog(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+	reader, ok := r.auditSink.(AuditReader)
+	if !ok {
+		return nil, fmt.Errorf("audit sink %T cannot read records", r.auditSink)
+	}
+	return reader.Records(ctx, filter)
+}
+
+// newAuditRecord returns a record of operation on the named config, made with ctx.
+func (r *Rigel) newAuditRecord(ctx context.Context, operation string) AuditRecord {
+	return AuditRecord{
+		Time:      time.Now().UTC(),
+		Actor:     ActorFromContext(ctx),
+		Source:    SourceFromContext(ctx),
+		Operation: operation,
+		App:       r.App,
+		Module:    r.Module,
+		Version:   r.Version,
+		Config:    r.Config,
+	}
+}
+
+// audit sets the outcome of records from err and hands them to the audit sink, if there is one.
+// Errors of the sink are ignored, since the operation has already been carried out.
+func (r *Rigel) audit(ctx context.Context, records []AuditRecord, err error) {
+	if r.auditSink == nil {
+		return
+	}
+	for _, record := range records {
+		record.Outcome = AuditSuccess
+		if err != nil {
+			record.Outcome = AuditFailure
+			record.Error = err.Error()
+		}
+		_ = r.auditSink.Record(ctx, record)
+	}
+}
+
+// auditWrite records a write of values and deletes, keyed by config key, that made changes and
+// ended with err. Values of secret fields are redacted.
+func (r *Rigel) auditWrite(ctx context.Context, operation string, values map[string]string, deletes []string, changes []HistoryEntry, err error) {
+	if r.auditSink == nil {
+		return
+	}
+
+	base := r.newAuditRecord(ctx, operation)
+	oldValues := make(map[string]string, len(changes))
+	for _, change := range changes {
+		oldValues[change.Key] = change.OldValue
+	}
+
+	// If the schema cannot be read, there is no telling which values are secret
+	schemaFields, schemaErr := r.getSchemaFields(ctx)
+	redact := func(configKey, value string) string {
+		if value == "" {
+			return ""
+		}
+		field := findField(schemaFields, configKey)
+		if schemaErr != nil || (field != nil && field.Type == types.TypeSecret) {
+			return RedactedValue
+		}
+		return value
+	}
+
+	var records []AuditRecord
+	for _, configKey := range sortedKeys(values) {
+		record := base
+		record.Key = configKey
+		record.OldValue = redact(configKey, oldValues[configKey])
+		record.NewValue = redact(configKey, values[configKey])
+		records = append(records, record)
+	}
+	for _, configKey := range deletes {
+		record := base
+		record.Key = configKey
+		record.OldValue = redact(configKey, oldValues[configKey])
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		records = append(records, base)
+	}
+	r.audit(ctx, records, err)
+}
+
+// StorageAuditSink keeps audit records in a Storage, outside the schemas and configs, so that every
+// client sharing the storage, such as rigelctl and the Rigel server, writes to the same audit log.
+type StorageAuditSink struct {
+	Storage types.Storage
+	seq     atomic.Uint64
+}
+
+// NewStorageAuditSink returns a StorageAuditSink that keeps its records in storage.
+func NewStorageAuditSink(storage types.Storage) *StorageAuditSink {
+	return &StorageAuditSink{Storage: storage}
+}
+
+// Record stores record under a key made of its time, so that keys sort in the order records were made.
+func (s *StorageAuditSink) Record(ctx context.Context, record AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	// The sequence number keeps records made in the same nanosecond apart
+	key := fmt.Sprintf("%s%020d-%06d", auditPrefix, record.Time.UnixNano(), s.seq.Add(1)%1000000)
+	if err := s.Storage.Put(ctx, key, string(b)); err != nil {
+		return fmt.Errorf("failed to store audit record: %w", err)
+	}
+	return nil
+}
+
+// Records returns the stored records selected by filter, oldest first.
+func (s *StorageAuditSink) Records(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+	keyVal, err := s.Storage.GetWithPrefix(ctx, auditPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit records: %w", err)
+	}
+
+	keys := make([]string, 0, len(keyVal))
+	for key := range keyVal {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	records := make([]AuditRecord, 0, len(keys))
+	for _, key := range keys {
+		var record AuditRecord
+		if err := json.Unmarshal([]byte(keyVal[key]), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse audit record %s: %w", key, err)
+		}
+		records = append(records, record)
+	}
+	return filter.apply(records), nil
+}
+
+// FileAuditSink appends audit records to a file, one JSON object per line.
+type FileAuditSink struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink opens, or creates, the file at path for appending audit records.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileAuditSink{path: path, file: file}, nil
+}
+
+// Record appends record to the file.
+func (s *FileAuditSink) Record(ctx context.Context, record AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Records reads the file and returns the records selected by filter, oldest first.
+func (s *FileAuditSink) Records(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	return readAuditRecords(file, filter)
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// readAuditRecords reads records written one per line and returns those selected by filter.
+func readAuditRecords(reader io.Reader, filter AuditFilter) ([]AuditRecord, error) {
+	var records []AuditRecord
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse audit record on line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return filter.apply(records), nil
+}
+
+// AuditSinks sends every record to each of its sinks, and reads records from the first sink that
+// can read them.
+type AuditSinks []AuditSink
+
+// Record hands record to every sink, and returns the errors of those that failed.
+func (s AuditSinks) Record(ctx context.Context, record AuditRecord) error {
+	var errs []error
+	for _, sink := range s {
+		if err := sink.Record(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Records returns the records selected by filter from the first sink that implements AuditReader.
+func (s AuditSinks) Records(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+	for _, sink := range s {
+		if reader, ok := sink.(AuditReader); ok {
+			return reader.Records(ctx, filter)
+		}
+	}
+	return nil, fmt.Errorf("none of the audit sinks can read records")
+}
@@ -0,0 +1,126 @@
+package rigel
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestAudit(t *testing.T) {
+	storage := memstore.New()
+	sink := NewStorageAuditSink(storage)
+	rigelClient := New(storage, "app", "module", 1, "config").WithAuditSink(sink)
+	ctx := WithSource(WithActor(context.Background(), "alice"), SourceCLI)
+
+	schema := types.Schema{
+		Fields: []types.Field{
+			{Name: "host", Type: types.TypeString},
+			{Name: "port", Type: types.TypeInt},
+			{Name: "password", Type: types.TypeSecret},
+		},
+		Version: 1,
+	}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "host", "first.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "host", "second.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "port", "not a number"); err == nil {
+		t.Fatalf("Expected a validation error, got nil")
+	}
+	if err := rigelClient.SetMany(ctx, map[string]string{"port": "8080", "password": "hunter2"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteConfig(WithSource(ctx, SourceAPI)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	records, err := rigelClient.AuditLog(ctx, AuditFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []struct{ op, key, old, new, outcome string }{
+		{AuditAddSchema, "", "", records[0].NewValue, AuditSuccess},
+		{AuditSet, "host", "", "first.example.com", AuditSuccess},
+		{AuditSet, "host", "first.example.com", "second.example.com", AuditSuccess},
+		{AuditSet, "port", "", "not a number", AuditFailure},
+		{AuditSetMany, "password", "", RedactedValue, AuditSuccess},
+		{AuditSetMany, "port", "", "8080", AuditSuccess},
+		{AuditDeleteConfig, "", "", "", AuditSuccess},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d records, got %d: %+v", len(want), len(records), records)
+	}
+	for i, w := range want {
+		got := records[i]
+		if got.Operation != w.op || got.Key != w.key || got.OldValue != w.old || got.NewValue != w.new || got.Outcome != w.outcome {
+			t.Errorf("Record %d: expected %+v, got %+v", i, w, got)
+		}
+		if got.Actor != "alice" || got.App != "app" || got.Module != "module" || got.Version != 1 {
+			t.Errorf("Record %d: unexpected actor or schema %+v", i, got)
+		}
+	}
+	if records[0].Config != "" || records[0].NewValue == "" {
+		t.Errorf("Expected the schema record to have the fields and no config, got %+v", records[0])
+	}
+	if records[3].Error == "" {
+		t.Errorf("Expected the failed record to have an error, got %+v", records[3])
+	}
+	if records[5].Source != SourceCLI || records[6].Source != SourceAPI {
+		t.Errorf("Expected the sources of the context, got %q and %q", records[5].Source, records[6].Source)
+	}
+
+	// Filters
+	failed, err := rigelClient.AuditLog(ctx, AuditFilter{Outcome: AuditFailure})
+	if err != nil || len(failed) != 1 || failed[0].Key != "port" {
+		t.Errorf("Expected the failed record, got %+v (err %v)", failed, err)
+	}
+	hosts, err := rigelClient.AuditLog(ctx, AuditFilter{Config: "config", Key: "host", Limit: 1})
+	if err != nil || len(hosts) != 1 || hosts[0].NewValue != "second.example.com" {
+		t.Errorf("Expected the latest change to host, got %+v (err %v)", hosts, err)
+	}
+	none, err := rigelClient.AuditLog(ctx, AuditFilter{Since: records[len(records)-1].Time.Add(1)})
+	if err != nil || len(none) != 0 {
+		t.Errorf("Expected no records, got %+v (err %v)", none, err)
+	}
+}
+
+func TestFileAuditSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer sink.Close()
+
+	rigelClient := newMemRigel(t, "config").WithAuditSink(sink)
+	if err := rigelClient.Set(ctx, "port", "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteKey(ctx, "port"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	records, err := rigelClient.AuditLog(ctx, AuditFilter{Key: "port"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 2 || records[0].Operation != AuditSet || records[1].Operation != AuditDeleteKey || records[1].OldValue != "8080" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestAuditLogWithoutReader(t *testing.T) {
+	rigelClient := newMemRigel(t, "config")
+	if _, err := rigelClient.AuditLog(context.Background(), AuditFilter{}); err == nil {
+		t.Errorf("Expected an error without an audit sink, got nil")
+	}
+}
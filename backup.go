@@ -0,0 +1,356 @@
+package rigel
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// BackupFormat identifies a backup archive and the version of its format.
+const BackupFormat = "rigel/backup/v1"
+
+// Names of the files in a backup archive.
+const (
+	backupManifestFile = "manifest.json"
+	backupDataFile     = "data.json"
+)
+
+// restoreBatchSize keeps each restore transaction below etcd's default limit of 128 operations.
+const restoreBatchSize = 100
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	Format   string      `json:"format"`
+	Created  time.Time   `json:"created"`
+	Prefix   string      `json:"prefix"`
+	Keys     int         `json:"keys"`
+	Schemas  []SchemaRef `json:"schemas"`
+	Configs  []ConfigRef `json:"configs"`
+	Checksum string      `json:"checksum"` // Checksum is the SHA-256 of the data file, as "sha256:<hex>"
+}
+
+// SchemaRef names a schema version.
+type SchemaRef struct {
+	App     string `json:"app"`
+	Module  string `json:"module"`
+	Version int    `json:"ver"`
+}
+
+// ConfigRef names a named config.
+type ConfigRef struct {
+	App     string `json:"app"`
+	Module  string `json:"module"`
+	Version int    `json:"ver"`
+	Config  string `json:"config"`
+}
+
+// BackupArchive is a backup read back by ReadBackup: its manifest and every key it holds.
+type BackupArchive struct {
+	Manifest BackupManifest
+	Data     map[string]string
+}
+
+// Backup writes every key under the Rigel prefix, that is every schema, field description and named
+// config with its metadata and history, to w as a gzipped tar archive holding a manifest and the keys.
+// The manifest lists the schemas and named configs and has a checksum of the keys.
+func Backup(ctx context.Context, storage types.Storage, w io.Writer) (*BackupManifest, error) {
+	data, err := storage.GetWithPrefix(ctx, rigelPrefix+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys: %w", err)
+	}
+	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keys: %w", err)
+	}
+	sum := sha256.Sum256(dataJSON)
+
+	manifest := &BackupManifest{
+		Format:   BackupFormat,
+		Created:  time.Now().UTC(),
+		Prefix:   rigelPrefix + "/",
+		Keys:     len(data),
+		Checksum: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	manifest.Schemas, manifest.Configs = backupContents(data)
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range []struct {
+		name string
+		body []byte
+	}{{backupManifestFile, manifestJSON}, {backupDataFile, dataJSON}} {
+		hdr := &tar.Header{Name: file.name, Mode: 0600, Size: int64(len(file.body)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write backup: %w", err)
+		}
+		if _, err := tw.Write(file.body); err != nil {
+			return nil, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return manifest, nil
+}
+
+// ReadBackup reads an archive written by Backup and checks it against the checksum in its manifest.
+func ReadBackup(r io.Reader) (*BackupArchive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+	}
+	if files[backupManifestFile] == nil || files[backupDataFile] == nil {
+		return nil, fmt.Errorf("backup archive must hold %s and %s", backupManifestFile, backupDataFile)
+	}
+
+	var archive BackupArchive
+	if err := json.Unmarshal(files[backupManifestFile], &archive.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if archive.Manifest.Format != BackupFormat {
+		return nil, fmt.Errorf("unsupported backup format %q, expected %q", archive.Manifest.Format, BackupFormat)
+	}
+	sum := sha256.Sum256(files[backupDataFile])
+	if checksum := "sha256:" + hex.EncodeToString(sum[:]); checksum != archive.Manifest.Checksum {
+		return nil, fmt.Errorf("backup is corrupt: checksum is %s, manifest says %s", checksum, archive.Manifest.Checksum)
+	}
+	if err := json.Unmarshal(files[backupDataFile], &archive.Data); err != nil {
+		return nil, fmt.Errorf("failed to parse backup data: %w", err)
+	}
+	return &archive, nil
+}
+
+// RestoreOptions selects what Restore restores.
+type RestoreOptions struct {
+	App    string // App, if set, restores only that app
+	Module string // Module, if set, restores only that module
+	DryRun bool   // DryRun checks the backup and reports what would be restored, without writing anything
+}
+
+// RestoreReport describes what Restore restored, or with DryRun would restore.
+type RestoreReport struct {
+	Keys    int
+	Schemas []SchemaRef
+	Configs []ConfigRef
+	DryRun  bool
+}
+
+// RestoreError is returned by Restore when named configs in the backup are not valid with their schemas.
+type RestoreError struct {
+	Configs map[ConfigRef][]string // Configs lists the problems of each invalid named config
+}
+
+func (e *RestoreError) Error() string {
+	refs := make([]ConfigRef, 0, len(e.Configs))
+	for ref := range e.Configs {
+		refs = append(refs, ref)
+	}
+	sortConfigRefs(refs)
+
+	var b strings.Builder
+	b.WriteString("backup has configs that are not valid with their schemas:")
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "\n%s/%s version %d config %s: %s", ref.App, ref.Module, ref.Version, ref.Config, strings.Join(e.Configs[ref], "; "))
+	}
+	return b.String()
+}
+
+// Restore writes the keys of a backup, or of the app and module chosen in opts, to storage. Every named
+// config is checked against its schema in the backup first, and if any is not valid, nothing is written
+// and a *RestoreError is returned. Existing keys are overwritten and keys not in the backup are left alone.
+// Keys are written in transactions of up to 100 keys, so a restore that fails part way may be partly written.
+func Restore(ctx context.Context, storage types.Storage, archive *BackupArchive, opts RestoreOptions) (*RestoreReport, error) {
+	selected := make(map[string]string)
+	for key, value := range archive.Data {
+		app, module, _, _, ok := parseRigelKey(key)
+		if !ok || (opts.App != "" && app != opts.App) || (opts.Module != "" && module != opts.Module) {
+			continue
+		}
+		selected[key] = value
+	}
+
+	report := &RestoreReport{Keys: len(selected), DryRun: opts.DryRun}
+	report.Schemas, report.Configs = backupContents(selected)
+	if problems := checkBackupConfigs(selected, report.Configs); len(problems) > 0 {
+		return report, &RestoreError{Configs: problems}
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	keys := sortedKeys(selected)
+	for start := 0; start < len(keys); start += restoreBatchSize {
+		end := start + restoreBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		ops := make([]types.Op, 0, end-start)
+		for _, key := range keys[start:end] {
+			ops = append(ops, types.PutOp(key, selected[key]))
+		}
+		if err := storage.Txn(ctx, ops); err != nil {
+			return report, fmt.Errorf("failed to restore keys, %d of %d written: %w", start, len(keys), err)
+		}
+	}
+	return report, nil
+}
+
+// checkBackupConfigs validates the values of every named config in data against the schema of its version in data.
+func checkBackupConfigs(data map[string]string, configs []ConfigRef) map[ConfigRef][]string {
+	layers := make(map[ConfigRef]map[string]string, len(configs))
+	for _, ref := range configs {
+		prefix := GetConfKeyPath(ref.App, ref.Module, ref.Version, ref.Config, "")
+		values := make(map[string]string)
+		for key, value := range data {
+			if strings.HasPrefix(key, prefix) {
+				values[strings.TrimPrefix(key, prefix)] = value
+			}
+		}
+		layers[ref] = values
+	}
+
+	problems := make(map[ConfigRef][]string)
+	for _, ref := range configs {
+		fieldsJSON, ok := data[GetSchemaFieldsPath(ref.App, ref.Module, ref.Version)]
+		if !ok {
+			problems[ref] = append(problems[ref], "schema version is not in the backup")
+			continue
+		}
+		var fields []types.Field
+		if err := json.Unmarshal([]byte(fieldsJSON), &fields); err != nil {
+			problems[ref] = append(problems[ref], fmt.Sprintf("schema fields cannot be read: %v", err))
+			continue
+		}
+
+		values := layers[ref]
+		for _, configKey := range sortedKeys(values) {
+			field := findField(fields, configKey)
+			if field == nil {
+				problems[ref] = append(problems[ref], fmt.Sprintf("%s has a value but is not in the schema", configKey))
+			} else if err := ValidateValue(values[configKey], field); err != nil {
+				problems[ref] = append(problems[ref], err.Error())
+			}
+		}
+
+		// Required fields may have their values in a parent under the same schema version
+		siblings := make(map[string]map[string]string)
+		parents := make(map[string]string)
+		for other, otherValues := range layers {
+			if other.App == ref.App && other.Module == ref.Module && other.Version == ref.Version {
+				siblings[other.Config] = otherValues
+				parents[other.Config] = data[GetConfParentPath(other.App, other.Module, other.Version, other.Config)]
+			}
+		}
+		if missing := missingRequiredFields(fields, mergeParents(ref.Config, siblings, parents)); len(missing) > 0 {
+			problems[ref] = append(problems[ref], fmt.Sprintf("missing required fields: %s", strings.Join(missing, ", ")))
+		}
+	}
+	return problems
+}
+
+// backupContents lists the schema versions and named configs that data has keys of. A named config is
+// listed if it has values or metadata, as ConfigExists reports it.
+func backupContents(data map[string]string) ([]SchemaRef, []ConfigRef) {
+	schemas := make(map[SchemaRef]bool)
+	configs := make(map[ConfigRef]bool)
+	for key := range data {
+		app, module, version, rest, ok := parseRigelKey(key)
+		if !ok {
+			continue
+		}
+		switch {
+		case len(rest) == 1 && rest[0] == schemaFieldsKey:
+			schemas[SchemaRef{App: app, Module: module, Version: version}] = true
+		case len(rest) >= 3 && rest[0] == schemaConfigKey && IsConfigDataKey(strings.Join(rest[2:], "/")):
+			configs[ConfigRef{App: app, Module: module, Version: version, Config: rest[1]}] = true
+		}
+	}
+
+	schemaRefs := make([]SchemaRef, 0, len(schemas))
+	for ref := range schemas {
+		schemaRefs = append(schemaRefs, ref)
+	}
+	sort.Slice(schemaRefs, func(i, j int) bool {
+		a, b := schemaRefs[i], schemaRefs[j]
+		if a.App != b.App {
+			return a.App < b.App
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Version < b.Version
+	})
+	configRefs := make([]ConfigRef, 0, len(configs))
+	for ref := range configs {
+		configRefs = append(configRefs, ref)
+	}
+	sortConfigRefs(configRefs)
+	return schemaRefs, configRefs
+}
+
+func sortConfigRefs(refs []ConfigRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.App != b.App {
+			return a.App < b.App
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Config < b.Config
+	})
+}
+
+// parseRigelKey splits a key under the Rigel prefix into its app, module and version, and the rest of its path.
+func parseRigelKey(key string) (app, module string, version int, rest []string, ok bool) {
+	if !strings.HasPrefix(key, rigelPrefix+"/") {
+		return "", "", 0, nil, false
+	}
+	parts := strings.Split(strings.TrimPrefix(key, rigelPrefix+"/"), "/")
+	if len(parts) < 4 {
+		return "", "", 0, nil, false
+	}
+	version, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", "", 0, nil, false
+	}
+	return parts[0], parts[1], version, parts[3:], true
+}
@@ -0,0 +1,154 @@
+package rigel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	source := memstore.New()
+	for _, app := range []string{"banking", "lending"} {
+		rigelClient := New(source, app, "transactions", 1, "prod")
+		schema := types.Schema{Version: 1, Description: app, Fields: []types.Field{
+			{Name: "host", Type: types.TypeString, Required: true, Description: "API host"},
+			{Name: "limit", Type: types.TypeInt},
+		}}
+		if err := rigelClient.AddSchema(ctx, schema); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := rigelClient.CreateConfig(ctx, "prod", "production", map[string]string{"host": app + ".example.com", "limit": "10"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Backup(ctx, source, &buf)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(manifest.Schemas) != 2 || len(manifest.Configs) != 2 {
+		t.Errorf("Expected 2 schemas and 2 configs in the manifest, got %+v", manifest)
+	}
+
+	archive, err := ReadBackup(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	all, err := source.GetWithPrefix(ctx, rigelPrefix+"/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(archive.Data, all) {
+		t.Errorf("Expected the backup to hold every key")
+	}
+
+	// A dry run writes nothing
+	target := memstore.New()
+	report, err := Restore(ctx, target, archive, RestoreOptions{App: "lending", DryRun: true})
+	if err != nil || len(report.Configs) != 1 || report.Configs[0].App != "lending" {
+		t.Fatalf("Expected the lending config to be selected, got %+v (err %v)", report, err)
+	}
+	if keys, _ := target.GetWithPrefix(ctx, rigelPrefix+"/"); len(keys) != 0 {
+		t.Errorf("Expected a dry run not to write anything, got %v", keys)
+	}
+
+	// Only the selected app is restored
+	if _, err := Restore(ctx, target, archive, RestoreOptions{App: "lending"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	restored := New(target, "lending", "transactions", 1, "prod")
+	if host, err := restored.Get(ctx, "host"); err != nil || host != "lending.example.com" {
+		t.Errorf("Expected the lending config to be restored, got %q (err %v)", host, err)
+	}
+	if description, _ := restored.ConfigDescription(ctx); description != "production" {
+		t.Errorf("Expected the config description to be restored, got %q", description)
+	}
+	if exists, _ := New(target, "banking", "transactions", 1, "prod").ConfigExists(ctx); exists {
+		t.Errorf("Expected the banking config not to be restored")
+	}
+
+	// Configs that are not valid with their schemas stop the restore
+	archive.Data[GetConfKeyPath("banking", "transactions", 1, "prod", "limit")] = "ten"
+	var restoreErr *RestoreError
+	if _, err := Restore(ctx, target, archive, RestoreOptions{}); !errors.As(err, &restoreErr) || len(restoreErr.Configs) != 1 {
+		t.Errorf("Expected a RestoreError for the banking config, got %v", err)
+	}
+
+	// A damaged archive is detected
+	gz, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tarball, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var corrupt bytes.Buffer
+	zw := gzip.NewWriter(&corrupt)
+	zw.Write(bytes.ReplaceAll(tarball, []byte("lending.example.com"), []byte("lendinG.example.com")))
+	zw.Close()
+	if _, err := ReadBackup(&corrupt); err == nil {
+		t.Errorf("Expected a corrupt archive to be rejected")
+	}
+}
+
+func TestRestoreHistoryAndMetadataOnlyConfigs(t *testing.T) {
+	ctx := context.Background()
+	source := memstore.New()
+	rigelClient := New(source, "app", "module", 1, "prod")
+	schema := types.Schema{Version: 1, Fields: []types.Field{{Name: "host", Type: types.TypeString}}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	hosts := []string{"first.example.com", "second.example.com", "third.example.com", "fourth.example.com"}
+	for _, host := range hosts {
+		if err := rigelClient.Set(ctx, "host", host); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	// A config without values is listed too
+	if err := rigelClient.CreateConfig(ctx, "empty", "", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Backup(ctx, source, &buf)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(manifest.Configs) != 2 || manifest.Configs[0].Config != "empty" {
+		t.Errorf("Expected the empty config in the manifest, got %+v", manifest.Configs)
+	}
+	archive, err := ReadBackup(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	target := memstore.New()
+	if _, err := Restore(ctx, target, archive, RestoreOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The restored entries share a revision, and keep the order they were recorded in
+	restored := New(target, "app", "module", 1, "prod")
+	entries, err := restored.History(ctx, "host")
+	if err != nil || len(entries) != len(hosts) {
+		t.Fatalf("Expected %d entries, got %+v (err %v)", len(hosts), entries, err)
+	}
+	for i, entry := range entries {
+		if entry.NewValue != hosts[i] {
+			t.Errorf("Expected entry %d to set %q, got %+v", i, hosts[i], entry)
+		}
+	}
+	if changed, err := restored.Rollback(ctx, entries[0].Revision); err != nil || len(changed) != 0 {
+		t.Errorf("Expected rolling back to the restored revision to change nothing, got %v (err %v)", changed, err)
+	}
+}
@@ -0,0 +1,183 @@
+package rigel
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// Binding keeps a config struct current with the named config it was bound to.
+// Each change is validated against the schema and, if valid, published as a new snapshot.
+// Snapshots are swapped in atomically and never modified afterwards, so readers
+// never see a half-updated struct.
+type Binding[T any] struct {
+	rigel   *Rigel
+	fields  []types.Field
+	byName  map[string]types.Field
+	current atomic.Pointer[T]
+
+	// values holds the raw stored values of the current snapshot; it is only used by the watch goroutine
+	values map[string]string
+
+	mu             sync.RWMutex
+	reloadHandlers []func(cfg *T)
+	errorHandlers  []func(err error)
+}
+
+// Bind loads the named config into configStruct, like LoadConfig, and then keeps watching it and its parents.
+// Every valid change produces a new snapshot, available through Load, and calls the handlers
+// registered with OnReload. A change that fails validation or conversion is not applied; the
+// previous snapshot stays in place and the error is passed to the handlers registered with OnError.
+//
+// configStruct only receives the initial values. It is not updated afterwards; use Load to get the
+// latest snapshot. Watching stops when ctx is cancelled.
+func Bind[T any](ctx context.Context, r *Rigel, configStruct *T) (*Binding[T], error) {
+	if configStruct == nil || reflect.TypeOf(configStruct).Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("configStruct must be a pointer to a struct")
+	}
+
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	b := &Binding[T]{
+		rigel:  r,
+		fields: schemaFields,
+		byName: make(map[string]types.Field, len(schemaFields)),
+	}
+	for _, field := range schemaFields {
+		b.byName[field.Name] = field
+	}
+
+	// Start watching before the initial load, so that no change made in between is missed.
+	// Events for changes already included in the load are harmless: they set the same values again.
+	// The watch is stopped again if the load fails, since nothing would read its events.
+	watchCtx, cancel := context.WithCancel(ctx)
+	events := make(chan types.Event)
+	if err := r.Storage.Watch(watchCtx, r.watchPrefix(), events); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to watch config: %w", err)
+	}
+
+	watch, err := r.newChainWatch(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	values := make(map[string]string, len(schemaFields))
+	for _, field := range schemaFields {
+		values[field.Name] = watch.value(field.Name)
+	}
+	snapshot, err := b.build(values)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	b.values = values
+	b.current.Store(snapshot)
+	*configStruct = *snapshot
+
+	go func() {
+		defer cancel()
+		for event := range events {
+			changes, err := watch.apply(ctx, event)
+			if err != nil {
+				b.fail(fmt.Errorf("failed to reload config parents: %w", err))
+				continue
+			}
+			b.apply(changes)
+		}
+	}()
+
+	return b, nil
+}
+
+// Load returns the latest snapshot of the config struct.
+// The returned struct must be treated as read-only, since other goroutines may be reading it too.
+func (b *Binding[T]) Load() *T {
+	return b.current.Load()
+}
+
+// OnReload registers a handler that is called with the new snapshot after each successful reload.
+func (b *Binding[T]) OnReload(handler func(cfg *T)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reloadHandlers = append(b.reloadHandlers, handler)
+}
+
+// OnError registers a handler that is called when a change is rejected.
+func (b *Binding[T]) OnError(handler func(err error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errorHandlers = append(b.errorHandlers, handler)
+}
+
+// apply validates the changes and, if they are all valid, publishes a new snapshot with all of them.
+func (b *Binding[T]) apply(changes []inheritedChange) {
+	values := make(map[string]string, len(b.values))
+	for k, v := range b.values {
+		values[k] = v
+	}
+	var changed []string
+	for _, change := range changes {
+		field, ok := b.byName[change.Key]
+		if !ok || change.New == b.values[change.Key] {
+			continue
+		}
+		if change.New != "" {
+			if err := ValidateValue(change.New, &field); err != nil {
+				b.fail(err)
+				return
+			}
+		}
+		values[change.Key] = change.New
+		changed = append(changed, change.Key)
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	snapshot, err := b.build(values)
+	if err != nil {
+		b.fail(fmt.Errorf("failed to reload config after change to %s: %w", strings.Join(changed, ", "), err))
+		return
+	}
+	b.values = values
+	b.current.Store(snapshot)
+
+	b.mu.RLock()
+	handlers := b.reloadHandlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(snapshot)
+	}
+}
+
+// build converts the raw values into a new config struct.
+func (b *Binding[T]) build(values map[string]string) (*T, error) {
+	configMap, err := buildConfigMap(b.fields, values)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := new(T)
+	if err := unmarshalConfig(configMap, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// fail passes err to the handlers registered with OnError.
+func (b *Binding[T]) fail(err error) {
+	b.mu.RLock()
+	handlers := b.errorHandlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(err)
+	}
+}
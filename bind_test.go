@@ -0,0 +1,89 @@
+package rigel
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rigelClient := newMemRigel(t, "config")
+
+	if err := rigelClient.Set(ctx, "host", "localhost"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "port", "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	type config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	var cfg config
+	binding, err := Bind(ctx, rigelClient, &cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 8080 {
+		t.Errorf("Expected initial values to be loaded, got %+v", cfg)
+	}
+
+	reloads := make(chan *config, 10)
+	errs := make(chan error, 10)
+	binding.OnReload(func(c *config) { reloads <- c })
+	binding.OnError(func(err error) { errs <- err })
+
+	if err := rigelClient.Set(ctx, "port", "9090"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case c := <-reloads:
+		if c.Host != "localhost" || c.Port != 9090 {
+			t.Errorf("Expected reloaded config with port 9090, got %+v", c)
+		}
+		if binding.Load() != c {
+			t.Errorf("Expected Load to return the reloaded snapshot")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected a reload, but didn't get one")
+	}
+
+	// A value that does not match the schema is rejected and the snapshot stays in place
+	before := binding.Load()
+	key := GetConfKeyPath("app", "module", 1, "config", "port")
+	if err := rigelClient.Storage.Put(ctx, key, "not-a-number"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected an error for an invalid value, but didn't get one")
+	}
+	if binding.Load() != before {
+		t.Errorf("Expected snapshot to be unchanged after an invalid value")
+	}
+
+	// The caller's struct only receives the initial values
+	if cfg.Port != 8080 {
+		t.Errorf("Expected the bound struct to keep its initial values, got %+v", cfg)
+	}
+}
+
+func TestBindStopsWatchOnError(t *testing.T) {
+	child := newInheritingRigel(t)
+	storage := &failingParentStorage{Storage: child.Storage}
+	child.Storage = storage
+
+	var cfg struct {
+		Host string `json:"host"`
+	}
+	if _, err := Bind(context.Background(), child, &cfg); err == nil {
+		t.Fatalf("Expected an error when the parents cannot be loaded")
+	}
+	if storage.watchCtx == nil || storage.watchCtx.Err() == nil {
+		t.Errorf("Expected the storage watch to be stopped")
+	}
+}
@@ -0,0 +1,106 @@
+package rigel
+
+import (
+	"github.com/remiges-tech/rigel/types"
+)
+
+// ChangeHandler is called when the value of a watched config key changes.
+// oldValue and newValue are converted to the Go type matching the schema field type: int,
+// float64, bool or string, time.Duration for durations, []string and []int for lists,
+// map[string]string for maps, json.RawMessage for json, Flag for flags, and the string itself
+// for urls and secrets. Like Get, a key the named config does not set has the value it inherits
+// from its parents, or failing that the default of the field, so deleting a key changes it to one
+// of those. A value is nil only if the key has none of these.
+// If the schema version does not exist, the values are the stored strings.
+type ChangeHandler func(oldValue, newValue any)
+
+// AnyChangeHandler is called when the value of any config key changes.
+// key is the config key (the schema field name); see ChangeHandler for the values.
+type AnyChangeHandler func(key string, oldValue, newValue any)
+
+// OnChange registers a handler that is called whenever the value of configKey changes.
+// Handlers are only called while WatchConfig is running. They are called one at a time,
+// in the order the changes happened, after the cache has been updated.
+func (r *Rigel) OnChange(configKey string, handler ChangeHandler) {
+	r.handlersMu.Lock()
+	defer r.handlersMu.Unlock()
+	if r.changeHandlers == nil {
+		r.changeHandlers = make(map[string][]ChangeHandler)
+	}
+	r.changeHandlers[configKey] = append(r.changeHandlers[configKey], handler)
+}
+
+// OnAnyChange registers a handler that is called whenever the value of any key in the named config changes.
+// See OnChange for when handlers are called.
+func (r *Rigel) OnAnyChange(handler AnyChangeHandler) {
+	r.handlersMu.Lock()
+	defer r.handlersMu.Unlock()
+	r.anyChangeHandlers = append(r.anyChangeHandlers, handler)
+}
+
+// notifyChange calls the handlers registered for the key changed by change.
+// fields maps schema field names to their definitions and is used to convert the values; if it is
+// nil, there is no schema and the values are passed on as they are.
+func (r *Rigel) notifyChange(change inheritedChange, fields map[string]types.Field) {
+	var oldValue, newValue any
+	if fields == nil {
+		// Without a schema there is no type to convert to
+		oldValue, newValue = untypedValue(change.Old), untypedValue(change.New)
+	} else {
+		field, ok := fields[change.Key]
+		if !ok {
+			// Not part of the schema, so there is no type to convert to
+			return
+		}
+		// Handlers get the values Get returns, which fall back to the default
+		oldStr, newStr := withDefault(change.Old, &field), withDefault(change.New, &field)
+		if oldStr == newStr {
+			return
+		}
+		oldValue = typedValue(oldStr, field.Type)
+		newValue = typedValue(newStr, field.Type)
+	}
+
+	r.handlersMu.RLock()
+	handlers := r.changeHandlers[change.Key]
+	anyHandlers := r.anyChangeHandlers
+	r.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(oldValue, newValue)
+	}
+	for _, handler := range anyHandlers {
+		handler(change.Key, oldValue, newValue)
+	}
+}
+
+// withDefault returns valueStr, or the default of the field if valueStr is empty.
+func withDefault(valueStr string, field *types.Field) string {
+	if valueStr == "" {
+		if def, ok := DefaultValue(field); ok {
+			return def
+		}
+	}
+	return valueStr
+}
+
+// untypedValue returns a stored value as it is, or nil if it is empty.
+func untypedValue(valueStr string) any {
+	if valueStr == "" {
+		return nil
+	}
+	return valueStr
+}
+
+// typedValue converts a stored value to the Go type of the field.
+// It returns nil for an empty value or one that cannot be converted.
+func typedValue(valueStr string, fieldType string) any {
+	if valueStr == "" {
+		return nil
+	}
+	value, err := convertToType(valueStr, fieldType)
+	if err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,135 @@
+package rigel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/rigel/memstore"
+)
+
+func TestOnChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rigelClient := newMemRigel(t, "config")
+
+	type change struct {
+		key      string
+		old, new any
+	}
+	portChanges := make(chan change, 10)
+	anyChanges := make(chan change, 10)
+	rigelClient.OnChange("port", func(oldValue, newValue any) {
+		portChanges <- change{"port", oldValue, newValue}
+	})
+	rigelClient.OnAnyChange(func(key string, oldValue, newValue any) {
+		anyChanges <- change{key, oldValue, newValue}
+	})
+
+	if err := rigelClient.WatchConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Changes to another named config must not be reported
+	other := New(rigelClient.Storage, "app", "module", 1, "config-eu")
+	if err := other.Set(ctx, "port", "9090"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := rigelClient.Set(ctx, "port", "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "host", "localhost"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "port", "8081"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteKey(ctx, "port"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectChange := func(ch <-chan change, want change) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("Expected change %+v, got %+v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected change %+v, but didn't get it", want)
+		}
+	}
+
+	// Values arrive converted to the schema type
+	expectChange(portChanges, change{"port", nil, 8080})
+	expectChange(portChanges, change{"port", 8080, 8081})
+	expectChange(portChanges, change{"port", 8081, nil})
+
+	expectChange(anyChanges, change{"port", nil, 8080})
+	expectChange(anyChanges, change{"host", nil, "localhost"})
+	expectChange(anyChanges, change{"port", 8080, 8081})
+	expectChange(anyChanges, change{"port", 8081, nil})
+}
+
+func TestOnChangeWithoutSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+
+	changes := make(chan any, 10)
+	rigelClient.OnChange("port", func(oldValue, newValue any) {
+		changes <- newValue
+	})
+	if err := rigelClient.WatchConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Without a schema, values arrive as they are stored
+	if err := rigelClient.Storage.Put(ctx, GetConfKeyPath("app", "module", 1, "config", "port"), "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case got := <-changes:
+		if got != "8080" {
+			t.Errorf("Expected %q, got %v", "8080", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected a change, but didn't get it")
+	}
+}
+
+func TestOnChangeFallsBackToDefault(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rigelClient := newMemRigelWithDefaults(t)
+	if err := rigelClient.CreateConfig(ctx, "config", "", map[string]string{"host": "localhost"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	changes := make(chan [2]any, 10)
+	rigelClient.OnChange("port", func(oldValue, newValue any) {
+		changes <- [2]any{oldValue, newValue}
+	})
+	if err := rigelClient.WatchConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Handlers get what Get returns, which is the default when the key is not set
+	if err := rigelClient.Set(ctx, "port", "9090"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteKey(ctx, "port"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range [][2]any{{8080, 9090}, {9090, 8080}} {
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("Expected change %v, got %v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected change %v, but didn't get it", want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// CloneOption configures CloneConfig.
+type CloneOption func(*cloneOptions)
+
+type cloneOptions struct {
+	overrides map[string]string
+	overwrite bool
+}
+
+// WithOverrides makes CloneConfig use the given values, keyed by config key, instead of the source's.
+func WithOverrides(values map[string]string) CloneOption {
+	return func(o *cloneOptions) {
+		o.overrides = values
+	}
+}
+
+// OverwriteDestination lets CloneConfig replace a destination config that already exists.
+// Keys of the destination that the clone has no value for are deleted.
+func OverwriteDestination() CloneOption {
+	return func(o *cloneOptions) {
+		o.overwrite = true
+	}
+}
+
+// CloneConfig copies the named config src to a new named config dst under the same schema version,
+// with the values given by WithOverrides taking the place of the source's. The description and the
+// parent of src are copied too, so that dst inherits what src inherits. The values are validated
+// against the schema and written in a single transaction, together with the metadata. CloneConfig
+// fails with a *ConfigExistsError if dst already exists, unless OverwriteDestination is given.
+func (r *Rigel) CloneConfig(ctx context.Context, src, dst string, opts ...CloneOption) (err error) {
+	var o cloneOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	source := r.at(r.Version, src)
+	target := r.at(r.Version, dst)
+	values := make(map[string]string)
+	var deletes []string
+	var changes []HistoryEntry
+	defer func() {
+		target.auditWrite(ctx, AuditCloneConfig, values, deletes, changes, err)
+	}()
+
+	if src == dst {
+		return fmt.Errorf("cannot clone config %s onto itself", src)
+	}
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	exists, err := source.ConfigExists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("config %s does not exist", src)
+	}
+	prefix := GetConfKeyPath(r.App, r.Module, r.Version, src, "")
+	keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	for key, value := range keyVal {
+		values[strings.TrimPrefix(key, prefix)] = value
+	}
+	for configKey, value := range o.overrides {
+		values[configKey] = value
+	}
+	description, err := source.ConfigDescription(ctx)
+	if err != nil {
+		return err
+	}
+	parent, err := source.ConfigParent(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := validateValues(schemaFields, values); err != nil {
+		return err
+	}
+	// Values the parent has count towards the required fields
+	effective := values
+	if parent != "" {
+		chain, err := target.checkParent(ctx, parent)
+		if err != nil {
+			return err
+		}
+		if effective, err = target.resolvedValues(ctx, chain[1:]); err != nil {
+			return err
+		}
+		for configKey, value := range values {
+			effective[configKey] = value
+		}
+	}
+	if missing := missingRequiredFields(schemaFields, effective); len(missing) > 0 {
+		return &MissingRequiredFieldsError{Fields: missing}
+	}
+
+	dstPrefix := GetConfKeyPath(r.App, r.Module, r.Version, dst, "")
+	current, err := r.Storage.GetWithPrefixAndRevision(ctx, dstPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	meta, err := r.Storage.GetWithPrefixAndRevision(ctx, GetConfPath(r.App, r.Module, r.Version, dst)+"/meta/")
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	if (len(current) > 0 || len(meta) > 0) && !o.overwrite {
+		return &ConfigExistsError{Config: dst}
+	}
+
+	// Every key of the destination is expected at the revision read here, or to be missing,
+	// so that a concurrent change to the destination fails the clone instead of being mixed into it
+	revisions := make(map[string]int64, len(values)+len(current))
+	for configKey := range values {
+		revisions[configKey] = current[dstPrefix+configKey].ModRevision
+	}
+	for key, kv := range current {
+		configKey := strings.TrimPrefix(key, dstPrefix)
+		if _, ok := values[configKey]; !ok {
+			deletes = append(deletes, configKey)
+			revisions[configKey] = kv.ModRevision
+		}
+	}
+	sort.Strings(deletes)
+
+	// So is the metadata. The destination keeps its creation time if it has one.
+	var extra []types.Op
+	copyMeta := func(key, value string) {
+		rev := meta[key].ModRevision
+		if value != "" {
+			extra = append(extra, types.PutOp(key, value).IfModRevision(rev))
+		} else if rev != 0 {
+			extra = append(extra, types.DeleteOp(key).IfModRevision(rev))
+		}
+	}
+	copyMeta(GetConfDescriptionPath(r.App, r.Module, r.Version, dst), description)
+	copyMeta(GetConfParentPath(r.App, r.Module, r.Version, dst), parent)
+	created := GetConfCreatedPath(r.App, r.Module, r.Version, dst)
+	createdAt := meta[created].Value
+	if createdAt == "" {
+		createdAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	copyMeta(created, createdAt)
+
+	changes, err = target.writeValues(ctx, values, deletes, revisions, extra...)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) && !o.overwrite {
+		// Nothing was expected to be there yet, so another caller created the destination first
+		return &ConfigExistsError{Config: dst}
+	}
+	if err != nil {
+		return err
+	}
+	// Values the destination inherited from a parent it had before may be cached
+	for _, field := range schemaFields {
+		if _, ok := values[field.Name]; !ok {
+			r.Cache.Delete(GetConfKeyPath(r.App, r.Module, r.Version, dst, field.Name))
+		}
+	}
+	return nil
+}
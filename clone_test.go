@@ -0,0 +1,118 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestCloneConfig(t *testing.T) {
+	ctx := context.Background()
+	storage := memstore.New()
+	rigelClient := New(storage, "app", "module", 1, "prod-us")
+	schema := types.Schema{Version: 1, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString, Required: true},
+		{Name: "region", Type: types.TypeString, Constraints: &types.Constraints{Enum: []string{"us", "eu"}}},
+		{Name: "debug", Type: types.TypeBool},
+	}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.CreateConfig(ctx, rigelClient.Config, "", map[string]string{"host": "us.example.com", "region": "us"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err := rigelClient.CloneConfig(ctx, "prod-us", "prod-eu", WithOverrides(map[string]string{"host": "eu.example.com", "region": "eu"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	eu := New(storage, "app", "module", 1, "prod-eu")
+	for key, want := range map[string]string{"host": "eu.example.com", "region": "eu"} {
+		if got, err := eu.Get(ctx, key); err != nil || got != want {
+			t.Errorf("Expected %s to be %q, got %q (err %v)", key, want, got, err)
+		}
+	}
+
+	// The destination is only replaced if asked to, and then keys the clone lacks are removed
+	var existsErr *ConfigExistsError
+	if err := rigelClient.CloneConfig(ctx, "prod-us", "prod-eu"); !errors.As(err, &existsErr) {
+		t.Errorf("Expected a ConfigExistsError, got %v", err)
+	}
+	if err := eu.Set(ctx, "debug", "true"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.CloneConfig(ctx, "prod-us", "prod-eu", OverwriteDestination()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	values, err := storage.GetWithPrefix(ctx, GetConfKeyPath("app", "module", 1, "prod-eu", ""))
+	if err != nil || len(values) != 2 || values[GetConfKeyPath("app", "module", 1, "prod-eu", "host")] != "us.example.com" {
+		t.Errorf("Expected prod-eu to be an exact copy of prod-us, got %v (err %v)", values, err)
+	}
+
+	// Overrides are validated, and nothing is written if one is invalid
+	var validationErrs ValidationErrors
+	err = rigelClient.CloneConfig(ctx, "prod-us", "prod-ap", WithOverrides(map[string]string{"region": "ap"}))
+	if !errors.As(err, &validationErrs) {
+		t.Errorf("Expected ValidationErrors, got %v", err)
+	}
+	if exists, _ := New(storage, "app", "module", 1, "prod-ap").ConfigExists(ctx); exists {
+		t.Errorf("Expected an invalid clone not to be written")
+	}
+	if err := rigelClient.CloneConfig(ctx, "missing", "prod-ap"); err == nil {
+		t.Errorf("Expected cloning a missing config to fail")
+	}
+}
+
+func TestCloneConfigMetadata(t *testing.T) {
+	ctx := context.Background()
+	storage := memstore.New()
+	rigelClient := New(storage, "app", "module", 1, "base")
+	schema := types.Schema{Version: 1, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString, Required: true},
+	}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.CreateConfig(ctx, "base", "", map[string]string{"host": "example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// child has no values of its own, and takes host from base
+	if err := rigelClient.CreateConfig(ctx, "child", "staging", nil, WithParent("base")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := rigelClient.CloneConfig(ctx, "child", "copy"); err != nil {
+		t.Fatalf("Expected an empty config to be cloned, got %v", err)
+	}
+	copied := New(storage, "app", "module", 1, "copy")
+	if description, err := copied.ConfigDescription(ctx); err != nil || description != "staging" {
+		t.Errorf("Expected description %q, got %q (err %v)", "staging", description, err)
+	}
+	if parent, err := copied.ConfigParent(ctx); err != nil || parent != "base" {
+		t.Errorf("Expected parent %q, got %q (err %v)", "base", parent, err)
+	}
+	if created, err := storage.Get(ctx, GetConfCreatedPath("app", "module", 1, "copy")); err != nil || created == "" {
+		t.Errorf("Expected the clone to be marked as created, got %q (err %v)", created, err)
+	}
+	if host, err := copied.Get(ctx, "host"); err != nil || host != "example.com" {
+		t.Errorf("Expected host to be inherited from base, got %q (err %v)", host, err)
+	}
+
+	// A destination that only has metadata exists too
+	if err := storage.Put(ctx, GetConfDescriptionPath("app", "module", 1, "described"), "described"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var existsErr *ConfigExistsError
+	if err := rigelClient.CloneConfig(ctx, "child", "described"); !errors.As(err, &existsErr) {
+		t.Errorf("Expected a ConfigExistsError, got %v", err)
+	}
+
+	// Overwriting base with a clone of child would make base its own parent
+	var cycle *ParentCycleError
+	if err := rigelClient.CloneConfig(ctx, "child", "base", OverwriteDestination()); !errors.As(err, &cycle) {
+		t.Errorf("Expected a ParentCycleError, got %v", err)
+	}
+}
@@ -3,9 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
-	"unsafe"
 
 	"github.com/remiges-tech/rigel/cmd/rigelctl/rigelctl"
 
@@ -17,6 +15,10 @@ import (
 func main() {
 	var etcdEndpoint, app, module, config string
 	var version int
+	var force bool
+
+	// rigelClient is created in PersistentPreRunE and shared by all subcommands
+	var rigelClient *rigel.Rigel
 
 	// Create the root command
 	rootCmd := &cobra.Command{
@@ -33,14 +35,10 @@ func main() {
 			}
 
 			// Create a new Rigel instance with the provided Storage interface
-			rigelClient := rigel.NewWithStorage(etcdStorage)
-
-			// Set the App and Module fields using the WithApp and WithModule methods
-			rigelClient = rigelClient.WithApp(app).WithModule(module).WithVersion(version)
-
-			// Store the Rigel client pointer in the command's annotations for later retrieval
-			cmd.Annotations = make(map[string]string)
-			cmd.Annotations["rigelClient"] = fmt.Sprintf("%p", rigelClient)
+			// and set the App, Module and Version fields using the with-prefixed methods.
+			// Changes are audited in etcd, where the server's /auditlog reads them.
+			rigelClient = rigel.NewWithStorage(etcdStorage).WithApp(app).WithModule(module).WithVersion(version).
+				WithAuditSink(rigel.NewStorageAuditSink(etcdStorage))
 			return nil
 		},
 	}
@@ -64,16 +62,17 @@ func main() {
 	addSchemaCmd := &cobra.Command{
 		Use:   "add [schema_file]",
 		Short: "Add a new schema from a file",
-		Args:  cobra.ExactArgs(1),
+		Long: "Add a schema version from a file.\n" +
+			"Schema versions are immutable: adding an identical schema again does nothing, and a changed\n" +
+			"schema is refused with a diff unless --force is given, which is only allowed if every\n" +
+			"named config under the version stays valid.",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Check if the required flags are provided
 			if app == "" || module == "" || version == 0 {
 				return fmt.Errorf("the 'app', 'module', and 'version' flags must be provided")
 			}
 
-			rigelClientPtr, _ := strconv.ParseUint(cmd.Annotations["rigelClient"], 0, 64)
-			rigelClient := (*rigel.Rigel)(unsafe.Pointer(uintptr(rigelClientPtr)))
-
 			// Check if the rigelClient is nil
 			if rigelClient == nil {
 				return fmt.Errorf("Failed to initialize Rigel client")
@@ -83,9 +82,68 @@ func main() {
 		},
 		SilenceUsage: true,
 	}
+	addSchemaCmd.Flags().BoolVar(&force, "force", false, "replace a changed schema if every named config stays valid with it")
 	// Add the 'addSchema' command to the 'schema' command
 	schemaCmd.AddCommand(addSchemaCmd)
 
+	// Create the 'diff' command under 'schema'
+	var diffFrom, diffTo int
+	diffSchemaCmd := &cobra.Command{
+		Use:   "diff --from <version> --to <version>",
+		Short: "Show the changes between two schema versions and whether they are backward-compatible",
+		Long: "Show the fields added and removed, type changes and tightened and loosened constraints between\n" +
+			"two schema versions, marking each change as compatible or breaking, and list the named configs\n" +
+			"under the --from version that would fail validation under the --to version.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" {
+				return fmt.Errorf("the 'app' and 'module' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			return rigelctl.DiffSchemaCommand(rigelClient, diffFrom, diffTo)
+		},
+		SilenceUsage: true,
+	}
+	diffSchemaCmd.Flags().IntVar(&diffFrom, "from", 0, "schema version to compare from")
+	diffSchemaCmd.Flags().IntVar(&diffTo, "to", 0, "schema version to compare to")
+	diffSchemaCmd.MarkFlagRequired("from")
+	diffSchemaCmd.MarkFlagRequired("to")
+	// Add the 'diffSchema' command to the 'schema' command
+	schemaCmd.AddCommand(diffSchemaCmd)
+
+	// Create the 'delete' command under 'schema'
+	deleteSchemaCmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a schema version",
+		Long: "Delete a schema version and its field descriptions.\n" +
+			"Deleting a schema that still has named configs is refused unless --force is given,\n" +
+			"in which case the named configs are deleted as well.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 {
+				return fmt.Errorf("the 'app', 'module', and 'version' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			return rigelctl.DeleteSchemaCommand(rigelClient, force)
+		},
+		SilenceUsage: true,
+	}
+	deleteSchemaCmd.Flags().BoolVar(&force, "force", false, "delete the schema even if named configs exist under it")
+	// Add the 'deleteSchema' command to the 'schema' command
+	schemaCmd.AddCommand(deleteSchemaCmd)
+
 	// Add the 'schema' command to the root command
 	rootCmd.AddCommand(schemaCmd)
 
@@ -110,11 +168,7 @@ func main() {
 				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
 			}
 
-			// Retrieve the Rigel client from the command's annotations
-			rigelClientPtr, _ := strconv.ParseUint(cmd.Annotations["rigelClient"], 0, 64)
-			rigelClient := (*rigel.Rigel)(unsafe.Pointer(uintptr(rigelClientPtr)))
-
-			// Check if the rigelClient is nil
+			// The Rigel client is created by the root command's PersistentPreRunE
 			if rigelClient == nil {
 				return fmt.Errorf("Failed to initialize Rigel client")
 			}
@@ -142,11 +196,7 @@ func main() {
 				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
 			}
 
-			// Retrieve the Rigel client from the command's annotations
-			rigelClientPtr, _ := strconv.ParseUint(cmd.Annotations["rigelClient"], 0, 64)
-			rigelClient := (*rigel.Rigel)(unsafe.Pointer(uintptr(rigelClientPtr)))
-
-			// Check if the rigelClient is nil
+			// The Rigel client is created by the root command's PersistentPreRunE
 			if rigelClient == nil {
 				return fmt.Errorf("Failed to initialize Rigel client")
 			}
@@ -163,9 +213,408 @@ func main() {
 	// Add the 'getConfig' command to the 'config' command
 	configCmd.AddCommand(getConfigCmd)
 
+	// Create the 'create' command under 'config'
+	var createDescription, createParent string
+	createConfigCmd := &cobra.Command{
+		Use:   "create [key=value]...",
+		Short: "Create a named config with its initial values",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			// Call the CreateConfigCommand function in the rigelctl package
+			return rigelctl.CreateConfigCommand(rigelClient, createDescription, createParent, args)
+		},
+	}
+	createConfigCmd.Flags().StringVar(&createDescription, "description", "", "description of the named config")
+	createConfigCmd.Flags().StringVar(&createParent, "parent", "", "named config to inherit the values not given from")
+
+	// Add the 'createConfig' command to the 'config' command
+	configCmd.AddCommand(createConfigCmd)
+
+	// Create the 'parent' command under 'config'
+	var parentClear bool
+	parentConfigCmd := &cobra.Command{
+		Use:   "parent [parent-config]",
+		Short: "Show or set the named config a config inherits the values it does not set from",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+			if parentClear && len(args) > 0 {
+				return fmt.Errorf("give either a parent config or --clear, not both")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			if len(args) == 0 && !parentClear {
+				return rigelctl.GetConfigParentCommand(rigelClient)
+			}
+			parent := ""
+			if len(args) > 0 {
+				parent = args[0]
+			}
+			return rigelctl.SetConfigParentCommand(rigelClient, parent)
+		},
+		SilenceUsage: true,
+	}
+	parentConfigCmd.Flags().BoolVar(&parentClear, "clear", false, "remove the parent, so the config no longer inherits")
+
+	// Add the 'parent' command to the 'config' command
+	configCmd.AddCommand(parentConfigCmd)
+
+	// Create the 'resolve' command under 'config'
+	resolveConfigCmd := &cobra.Command{
+		Use:   "resolve",
+		Short: "Show the effective value of every key of a named config and where it comes from",
+		Long: "Show the effective value of every field of the schema: the config's own value, else the value of\n" +
+			"its nearest parent that has one, else the schema default. Secret values are redacted.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			return rigelctl.ResolveConfigCommand(rigelClient)
+		},
+		SilenceUsage: true,
+	}
+
+	// Add the 'resolve' command to the 'config' command
+	configCmd.AddCommand(resolveConfigCmd)
+
+	// Create the 'set-many' command under 'config'
+	setManyConfigCmd := &cobra.Command{
+		Use:   "set-many key=value...",
+		Short: "Set several config keys at once; either all of them are set or none is",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			// Call the SetManyConfigCommand function in the rigelctl package
+			return rigelctl.SetManyConfigCommand(rigelClient, args)
+		},
+	}
+
+	// Add the 'setMany' command to the 'config' command
+	configCmd.AddCommand(setManyConfigCmd)
+
+	// Create the 'clone' command under 'config'
+	var cloneOverwrite bool
+	cloneConfigCmd := &cobra.Command{
+		Use:   "clone <destination> [key=value]...",
+		Short: "Copy a named config to a new named config, overriding the given keys",
+		Long: "Copy the named config given by --config to a new named config. Values given as key=value\n" +
+			"replace the copied ones. The new config is validated against the schema and written in a\n" +
+			"single transaction. An existing destination is only replaced if --overwrite is given.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			// Call the CloneConfigCommand function in the rigelctl package
+			return rigelctl.CloneConfigCommand(rigelClient, args[0], args[1:], cloneOverwrite)
+		},
+	}
+	cloneConfigCmd.Flags().BoolVar(&cloneOverwrite, "overwrite", false, "replace the destination config if it already exists")
+
+	// Add the 'clone' command to the 'config' command
+	configCmd.AddCommand(cloneConfigCmd)
+
+	// Create the 'export' command under 'config'
+	var exportFormat, exportOutput string
+	exportConfigCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write a named config, with its description and values, as a JSON or YAML document",
+		Long: "Write a named config as a self-describing document naming its app, module, version and config,\n" +
+			"with its description and values, which 'config import' reads back. Secret values are included.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			return rigelctl.ExportConfigCommand(rigelClient, exportFormat, exportOutput)
+		},
+		SilenceUsage: true,
+	}
+	exportConfigCmd.Flags().StringVar(&exportFormat, "format", rigel.FormatJSON, "document format, json or yaml")
+	exportConfigCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "file to write the document to instead of stdout")
+
+	// Add the 'export' command to the 'config' command
+	configCmd.AddCommand(exportConfigCmd)
+
+	// Create the 'import' command under 'config'
+	var importDryRun bool
+	importConfigCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Make a named config match a document written by 'config export'",
+		Long: "Validate a JSON or YAML document written by 'config export' against its schema version, show how it\n" +
+			"differs from the stored config and apply it in a single transaction, creating the config if need be.\n" +
+			"Keys the document does not have are removed. The --app, --module, --version and --config flags, if\n" +
+			"given, replace those named in the document.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the config name on the rigelClient
+			rigelClient = rigelClient.WithConfig(config)
+
+			return rigelctl.ImportConfigCommand(rigelClient, args[0], importDryRun)
+		},
+		SilenceUsage: true,
+	}
+	importConfigCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "only show the changes the import would make")
+
+	// Add the 'import' command to the 'config' command
+	configCmd.AddCommand(importConfigCmd)
+
+	// Create the 'history' command under 'config'
+	historyConfigCmd := &cobra.Command{
+		Use:   "history [key]",
+		Short: "Show the changes made to a config key, or to the whole named config if no key is given",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			var key string
+			if len(args) == 1 {
+				key = args[0]
+			}
+
+			// Call the HistoryConfigCommand function in the rigelctl package
+			return rigelctl.HistoryConfigCommand(rigelClient, key)
+		},
+	}
+
+	// Add the 'history' command to the 'config' command
+	configCmd.AddCommand(historyConfigCmd)
+
+	// Create the 'rollback' command under 'config'
+	var rollbackTo int64
+	rollbackConfigCmd := &cobra.Command{
+		Use:   "rollback --to <revision>",
+		Short: "Restore a named config to its state at a revision shown by 'config history'",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			// Call the RollbackConfigCommand function in the rigelctl package
+			return rigelctl.RollbackConfigCommand(rigelClient, rollbackTo)
+		},
+	}
+	rollbackConfigCmd.Flags().Int64Var(&rollbackTo, "to", 0, "revision to roll back to")
+	rollbackConfigCmd.MarkFlagRequired("to")
+
+	// Add the 'rollback' command to the 'config' command
+	configCmd.AddCommand(rollbackConfigCmd)
+
+	// Create the 'migrate' command under 'config'
+	var migrateFrom, migrateTo int
+	var migrateMapping string
+	var migrateDryRun bool
+	migrateConfigCmd := &cobra.Command{
+		Use:   "migrate --from <version> --to <version>",
+		Short: "Copy a named config to a new schema version",
+		Long: "Copy a named config from one schema version to another. Keys with the same name are copied,\n" +
+			"keys are renamed as given in the --mapping file, a JSON object of old to new field names, and\n" +
+			"new fields are filled from their defaults. The values are validated against the new version and\n" +
+			"written in a single transaction. What the migration does is printed first; with --dry-run,\n" +
+			"nothing is written.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || config == "" {
+				return fmt.Errorf("the 'app', 'module', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the config name on the rigelClient
+			rigelClient = rigelClient.WithConfig(config)
+
+			return rigelctl.MigrateConfigCommand(rigelClient, migrateFrom, migrateTo, migrateMapping, migrateDryRun)
+		},
+		SilenceUsage: true,
+	}
+	migrateConfigCmd.Flags().IntVar(&migrateFrom, "from", 0, "schema version to migrate the config from")
+	migrateConfigCmd.Flags().IntVar(&migrateTo, "to", 0, "schema version to migrate the config to")
+	migrateConfigCmd.Flags().StringVar(&migrateMapping, "mapping", "", "JSON file mapping old field names to new ones")
+	migrateConfigCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "only show what the migration would do")
+	migrateConfigCmd.MarkFlagRequired("from")
+	migrateConfigCmd.MarkFlagRequired("to")
+
+	// Add the 'migrate' command to the 'config' command
+	configCmd.AddCommand(migrateConfigCmd)
+
+	// Create the 'delete' command under 'config'
+	deleteConfigCmd := &cobra.Command{
+		Use:   "delete [key]",
+		Short: "Delete a config key, or the whole named config if no key is given",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the required flags are provided
+			if app == "" || module == "" || version == 0 || config == "" {
+				return fmt.Errorf("the 'app', 'module', 'version', and 'config' flags must be provided")
+			}
+
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			// Set the version and config name on the rigelClient
+			rigelClient = rigelClient.WithVersion(version).WithConfig(config)
+
+			// Call the DeleteConfigCommand function in the rigelctl package
+			var key string
+			if len(args) == 1 {
+				key = args[0]
+			}
+			return rigelctl.DeleteConfigCommand(rigelClient, key)
+		},
+	}
+
+	// Add the 'deleteConfig' command to the 'config' command
+	configCmd.AddCommand(deleteConfigCmd)
+
 	// Add the 'config' command to the root command
 	rootCmd.AddCommand(configCmd)
 
+	// Create the 'backup' command
+	var backupOutput string
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Write every schema and named config to a backup archive",
+		Long: "Write every key under /remiges/rigel, that is every schema, field description and named config with\n" +
+			"its metadata and history, to a gzipped tar archive with a manifest and a checksum of the keys.\n" +
+			"Secret values are included.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			return rigelctl.BackupCommand(rigelClient, backupOutput)
+		},
+		SilenceUsage: true,
+	}
+	backupCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "file to write the archive to (required)")
+	backupCmd.MarkFlagRequired("output")
+
+	// Add the 'backup' command to the root command
+	rootCmd.AddCommand(backupCmd)
+
+	// Create the 'restore' command
+	var restoreDryRun bool
+	restoreCmd := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restore schemas and named configs from a backup archive",
+		Long: "Check a backup archive against its checksum and every named config in it against its schema, then\n" +
+			"write its keys. The --app and --module flags, if given, restore only that app and module. Existing\n" +
+			"keys are overwritten and keys not in the backup are left alone.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if the rigelClient is nil
+			if rigelClient == nil {
+				return fmt.Errorf("Failed to initialize Rigel client")
+			}
+
+			return rigelctl.RestoreCommand(rigelClient, args[0], rigel.RestoreOptions{App: app, Module: module, DryRun: restoreDryRun})
+		},
+		SilenceUsage: true,
+	}
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "only check the archive and show what would be restored")
+
+	// Add the 'restore' command to the root command
+	rootCmd.AddCommand(restoreCmd)
+
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -3,9 +3,13 @@ package rigelctl
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/user"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/remiges-tech/rigel"
@@ -42,10 +46,14 @@ func AddSchemaCommand(client *rigel.Rigel, cmd *cobra.Command, args []string) er
 
 	schema.Version = client.Version
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := commandContext()
 	defer cancel()
+	var opts []rigel.AddSchemaOption
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		opts = append(opts, rigel.ForceOverwrite())
+	}
 	// Call AddSchema
-	err = client.AddSchema(ctx, schema)
+	err = client.AddSchema(ctx, schema, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to add schema: %v", err)
 	}
@@ -58,7 +66,7 @@ func AddSchemaCommand(client *rigel.Rigel, cmd *cobra.Command, args []string) er
 
 func SetConfigCommand(client *rigel.Rigel, key string, value string) error {
 	// Set the config key and its value using the Set function
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := commandContext()
 	defer cancel()
 
 	err := client.Set(ctx, key, value)
@@ -71,7 +79,7 @@ func SetConfigCommand(client *rigel.Rigel, key string, value string) error {
 }
 
 func GetConfigCommand(client *rigel.Rigel, key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := commandContext()
 	defer cancel()
 
 	value, err := client.Get(ctx, key)
@@ -83,6 +91,464 @@ func GetConfigCommand(client *rigel.Rigel, key string) error {
 	return nil
 }
 
+// DeleteConfigCommand deletes a single config key, or the whole named config when key is empty.
+func DeleteConfigCommand(client *rigel.Rigel, key string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if key == "" {
+		err := client.DeleteConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to delete config: %v", err)
+		}
+		fmt.Printf("Config '%s' deleted successfully\n", client.Config)
+		return nil
+	}
+
+	err := client.DeleteKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("Failed to delete config key: %v", err)
+	}
+
+	fmt.Printf("Config key '%s' deleted successfully\n", key)
+	return nil
+}
+
+// DeleteSchemaCommand deletes the schema version the client points to.
+// Named configs under the schema are deleted only if force is true.
+func DeleteSchemaCommand(client *rigel.Rigel, force bool) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	err := client.DeleteSchema(ctx, force)
+	if err != nil {
+		var inUse *rigel.SchemaInUseError
+		if errors.As(err, &inUse) {
+			return fmt.Errorf("%v (use --force to delete them too)", err)
+		}
+		return fmt.Errorf("failed to delete schema: %v", err)
+	}
+
+	fmt.Println("Schema deleted successfully.")
+	fmt.Printf("app: %s \nmodule: %s \nversion: %d\n", client.App, client.Module, client.Version)
+	return nil
+}
+
+// CreateConfigCommand creates the named config with the given description from "key=value" arguments.
+// All required fields without a default must be given, unless parent, if not empty, has a value for them.
+func CreateConfigCommand(client *rigel.Rigel, description, parent string, args []string) error {
+	values, err := parseKeyValues(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	var opts []rigel.CreateOption
+	if parent != "" {
+		opts = append(opts, rigel.WithParent(parent))
+	}
+	err = client.CreateConfig(ctx, client.Config, description, values, opts...)
+	if err != nil {
+		return fmt.Errorf("Failed to create config: %v", err)
+	}
+
+	fmt.Printf("Config '%s' created successfully\n", client.Config)
+	return nil
+}
+
+// GetConfigParentCommand prints the parent of the named config.
+func GetConfigParentCommand(client *rigel.Rigel) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	parent, err := client.ConfigParent(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get config parent: %v", err)
+	}
+	if parent == "" {
+		fmt.Printf("Config '%s' has no parent\n", client.Config)
+		return nil
+	}
+	fmt.Println(parent)
+	return nil
+}
+
+// SetConfigParentCommand makes parent the parent of the named config, or removes its parent if parent is empty.
+func SetConfigParentCommand(client *rigel.Rigel, parent string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if err := client.SetConfigParent(ctx, parent); err != nil {
+		return fmt.Errorf("Failed to set config parent: %v", err)
+	}
+	if parent == "" {
+		fmt.Printf("Config '%s' no longer has a parent\n", client.Config)
+		return nil
+	}
+	fmt.Printf("Config '%s' now inherits from '%s'\n", client.Config, parent)
+	return nil
+}
+
+// ResolveConfigCommand prints the effective value of every key of the named config and where it comes from.
+func ResolveConfigCommand(client *rigel.Rigel) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	resolved, err := client.ResolveConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve config: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tFROM")
+	for _, rv := range resolved {
+		from := rv.Config
+		switch {
+		case rv.Default:
+			from = "(default)"
+		case from == "":
+			from = "(unset)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rv.Key, rv.Value, from)
+	}
+	return w.Flush()
+}
+
+// SetManyConfigCommand sets several keys of a named config in a single transaction.
+func SetManyConfigCommand(client *rigel.Rigel, args []string) error {
+	values, err := parseKeyValues(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	err = client.SetMany(ctx, values)
+	if err != nil {
+		return fmt.Errorf("Failed to set config values: %v", err)
+	}
+
+	fmt.Printf("%d values set in config '%s'\n", len(values), client.Config)
+	return nil
+}
+
+// CloneConfigCommand copies the named config to a new named config dst, with the key=value
+// arguments overriding the copied values.
+func CloneConfigCommand(client *rigel.Rigel, dst string, args []string, overwrite bool) error {
+	overrides, err := parseKeyValues(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	opts := []rigel.CloneOption{rigel.WithOverrides(overrides)}
+	if overwrite {
+		opts = append(opts, rigel.OverwriteDestination())
+	}
+	err = client.CloneConfig(ctx, client.Config, dst, opts...)
+	if err != nil {
+		return fmt.Errorf("Failed to clone config: %v", err)
+	}
+
+	fmt.Printf("Config '%s' cloned to '%s'\n", client.Config, dst)
+	return nil
+}
+
+// ExportConfigCommand writes the named config as a document in the given format, to file or, if file is empty, to stdout.
+func ExportConfigCommand(client *rigel.Rigel, format, file string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	doc, err := client.ExportConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to export config: %v", err)
+	}
+	b, err := doc.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	if file == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	// The document holds secret values, so only the owner may read it
+	if err := os.WriteFile(file, b, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	fmt.Printf("Config '%s' exported to %s\n", client.Config, file)
+	return nil
+}
+
+// ImportConfigCommand imports a config document from file. The app, module, version and config set on
+// client, if any, take the place of those in the document. The changes are printed first and, with
+// dryRun, not applied.
+func ImportConfigCommand(client *rigel.Rigel, file string, dryRun bool) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	doc, err := rigel.ParseConfigDocument(b)
+	if err != nil {
+		return err
+	}
+	if client.App != "" {
+		doc.App = client.App
+	}
+	if client.Module != "" {
+		doc.Module = client.Module
+	}
+	if client.Version != 0 {
+		doc.Version = client.Version
+	}
+	if client.Config != "" {
+		doc.Config = client.Config
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	diff, err := client.PreviewImport(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("Failed to import config: %v", err)
+	}
+	if diff.Empty() {
+		fmt.Printf("Config '%s' already matches %s\n", doc.Config, file)
+		return nil
+	}
+	fmt.Printf("Importing %s into config '%s' of %s/%s version %d:\n%s\n", file, doc.Config, doc.App, doc.Module, doc.Version, diff)
+	if dryRun {
+		fmt.Println("Dry run, nothing was written")
+		return nil
+	}
+
+	if _, err := client.ImportConfig(ctx, doc); err != nil {
+		return fmt.Errorf("Failed to import config: %v", err)
+	}
+	fmt.Printf("Config '%s' imported\n", doc.Config)
+	return nil
+}
+
+// BackupCommand writes every key under the Rigel prefix to a backup archive in file.
+func BackupCommand(client *rigel.Rigel, file string) error {
+	ctx, cancel := commandContextTimeout(time.Minute)
+	defer cancel()
+
+	// The archive holds secret values, so only the owner may read it
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	manifest, err := rigel.Backup(ctx, client.Storage, f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("Failed to back up: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	fmt.Printf("Backed up %d keys, %d schemas and %d configs to %s (%s)\n",
+		manifest.Keys, len(manifest.Schemas), len(manifest.Configs), file, manifest.Checksum)
+	return nil
+}
+
+// RestoreCommand restores the backup archive in file, or the app and module chosen in opts.
+// With opts.DryRun the archive is only checked and what would be restored is printed.
+func RestoreCommand(client *rigel.Rigel, file string, opts rigel.RestoreOptions) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	archive, err := rigel.ReadBackup(f)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContextTimeout(time.Minute)
+	defer cancel()
+
+	report, err := rigel.Restore(ctx, client.Storage, archive, opts)
+	if err != nil {
+		return fmt.Errorf("Failed to restore: %v", err)
+	}
+	if report.Keys == 0 {
+		fmt.Printf("Nothing to restore from %s\n", file)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tMODULE\tVERSION\tCONFIG")
+	for _, ref := range report.Schemas {
+		fmt.Fprintf(w, "%s\t%s\t%d\t-\n", ref.App, ref.Module, ref.Version)
+	}
+	for _, ref := range report.Configs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", ref.App, ref.Module, ref.Version, ref.Config)
+	}
+	w.Flush()
+	if report.DryRun {
+		fmt.Printf("Dry run, %d keys would be restored from %s\n", report.Keys, file)
+		return nil
+	}
+	fmt.Printf("Restored %d keys from %s\n", report.Keys, file)
+	return nil
+}
+
+// HistoryConfigCommand prints the recorded changes of a config key, or of the whole named config when key is empty.
+func HistoryConfigCommand(client *rigel.Rigel, key string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	entries, err := client.History(ctx, key)
+	if err != nil {
+		return fmt.Errorf("Failed to get history: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tTIME\tACTOR\tKEY\tOLD\tNEW")
+	for _, e := range entries {
+		newValue := e.NewValue
+		if e.Deleted {
+			newValue = "(deleted)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", e.Revision, e.Time.Local().Format(time.RFC3339), e.Actor, e.Key, e.OldValue, newValue)
+	}
+	return w.Flush()
+}
+
+// RollbackConfigCommand restores the named config to its state at the given revision.
+func RollbackConfigCommand(client *rigel.Rigel, rev int64) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	changed, err := client.Rollback(ctx, rev)
+	if err != nil {
+		return fmt.Errorf("Failed to roll back config: %v", err)
+	}
+
+	if len(changed) == 0 {
+		fmt.Printf("Config '%s' is already as it was at revision %d\n", client.Config, rev)
+		return nil
+	}
+	fmt.Printf("Config '%s' rolled back to revision %d, changed keys: %s\n", client.Config, rev, strings.Join(changed, ", "))
+	return nil
+}
+
+// DiffSchemaCommand prints how schema version to differs from version from, whether each change is
+// backward-compatible, and which named configs under version from would not be valid under version to.
+func DiffSchemaCommand(client *rigel.Rigel, from, to int) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	compatibility, err := client.CompareSchemaVersions(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("Failed to compare schema versions: %v", err)
+	}
+
+	if len(compatibility.Changes) == 0 {
+		fmt.Printf("Schema versions %d and %d are the same\n", from, to)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tKIND\tCHANGE\tCOMPATIBILITY")
+		for _, c := range compatibility.Changes {
+			compat := "compatible"
+			if c.Breaking {
+				compat = "breaking"
+			}
+			field := c.Field
+			if field == "" {
+				field = "(schema)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", field, c.Kind, c.Detail, compat)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(compatibility.InvalidConfigs) == 0 {
+		fmt.Printf("\nEvery named config under version %d is valid under version %d\n", from, to)
+		return nil
+	}
+	configs := make([]string, 0, len(compatibility.InvalidConfigs))
+	for config := range compatibility.InvalidConfigs {
+		configs = append(configs, config)
+	}
+	sort.Strings(configs)
+	fmt.Printf("\nNamed configs under version %d that would fail validation under version %d:\n", from, to)
+	for _, config := range configs {
+		fmt.Printf("  %s: %s\n", config, strings.Join(compatibility.InvalidConfigs[config], "; "))
+	}
+	return nil
+}
+
+// MigrateConfigCommand copies the named config from schema version from to version to, renaming fields
+// as given in the JSON mapping file if one is given. It prints what the migration does first and, with
+// dryRun, stops there.
+func MigrateConfigCommand(client *rigel.Rigel, from, to int, mappingFile string, dryRun bool) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	opts := []rigel.MigrateOption{rigel.DryRun()}
+	if mappingFile != "" {
+		renames, err := rigel.LoadRenames(mappingFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, rigel.RenameFields(renames))
+	}
+
+	report, err := client.MigrateConfig(ctx, from, to, client.Config, opts...)
+	if report != nil && (len(report.Values) > 0 || len(report.Dropped) > 0) {
+		fmt.Printf("Migrating config '%s' from version %d to version %d:\n%s\n", client.Config, from, to, report)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to migrate config: %v", err)
+	}
+	if dryRun {
+		fmt.Println("Dry run, nothing was written")
+		return nil
+	}
+
+	if _, err := client.MigrateConfig(ctx, from, to, client.Config, opts[1:]...); err != nil {
+		return fmt.Errorf("Failed to migrate config: %v", err)
+	}
+	fmt.Printf("Config '%s' migrated to version %d\n", client.Config, to)
+	return nil
+}
+
+// commandContext returns the context a command runs with. It times out after 5 seconds and records
+// the user running rigelctl as the actor of any change made with it, and rigelctl as its source.
+func commandContext() (context.Context, context.CancelFunc) {
+	return commandContextTimeout(time.Second * 5)
+}
+
+// commandContextTimeout is commandContext with the given timeout, for commands that read or write many keys.
+func commandContextTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := rigel.WithSource(context.Background(), rigel.SourceCLI)
+	if u, err := user.Current(); err == nil {
+		ctx = rigel.WithActor(ctx, u.Username)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// parseKeyValues parses arguments of the form key=value.
+func parseKeyValues(args []string) (map[string]string, error) {
+	values := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected key=value", arg)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
 func ValidateSchema(schemaBytes []byte) error {
 	schemaLoader := gojsonschema.NewStringLoader(string(schemaBytes))
 	jsonSchemaLoader := gojsonschema.NewStringLoader(RigelSchemaJSON)
@@ -100,5 +566,14 @@ func ValidateSchema(schemaBytes []byte) error {
 		return fmt.Errorf("invalid schema:\n%s", strings.Join(errMessages, "\n"))
 	}
 
+	// Check what JSON Schema cannot express, such as defaults matching the field type
+	var schema types.Schema
+	if err := json.Unmarshal(schemaBytes, &schema); err != nil {
+		return fmt.Errorf("failed to parse schema: %v", err)
+	}
+	if err := rigel.ValidateFields(schema.Fields); err != nil {
+		return fmt.Errorf("invalid schema:\n%v", err)
+	}
+
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/remiges-tech/rigel"
 	"github.com/remiges-tech/rigel/mocks"
+	"github.com/remiges-tech/rigel/types"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +16,12 @@ func TestAddSchemaFromFile(t *testing.T) {
 	// Create a mock Rigel client
 	mockRigelClient := &rigel.Rigel{
 		Storage: &mocks.MockStorage{
+			GetWithRevisionFunc: func(ctx context.Context, key string) (types.KeyValue, error) {
+				return types.KeyValue{}, nil
+			},
+			TxnFunc: func(ctx context.Context, ops []types.Op) error {
+				return nil
+			},
 			PutFunc: func(ctx context.Context, key string, value string) error {
 				return nil
 			},
@@ -107,12 +114,146 @@ func TestValidateSchema(t *testing.T) {
 		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
 	}`)
 
+	validSchemaDefault := []byte(`{
+		"fields": [
+			{
+				"name": "transactionTimeout",
+				"type": "int",
+				"description": "Defines the maximum duration (in seconds) a transaction should take before timing out.",
+				"default": 30,
+				"required": true,
+				"constraints": {
+					"min": 1,
+					"max": 60
+				}
+			}
+		],
+		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
+	}`)
+
+	invalidSchemaDefault := []byte(`{
+		"fields": [
+			{
+				"name": "transactionTimeout",
+				"type": "int",
+				"description": "Defines the maximum duration (in seconds) a transaction should take before timing out.",
+				"default": 90,
+				"constraints": {
+					"min": 1,
+					"max": 60
+				}
+			}
+		],
+		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
+	}`)
+
+	validSchemaRichTypes := []byte(`{
+		"fields": [
+			{
+				"name": "retryDelay",
+				"type": "duration",
+				"description": "Delay between retries.",
+				"default": "1m30s"
+			},
+			{
+				"name": "allowedCurrencies",
+				"type": "[]string",
+				"description": "Currencies accepted by the gateway.",
+				"default": ["INR", "USD"]
+			},
+			{
+				"name": "headers",
+				"type": "map[string]string",
+				"description": "Headers sent with every request.",
+				"default": {"X-Source": "rigel"}
+			},
+			{
+				"name": "callbackURL",
+				"type": "url",
+				"description": "URL notified when a payment completes."
+			},
+			{
+				"name": "apiKey",
+				"type": "secret",
+				"description": "Key used to authenticate with the gateway."
+			}
+		],
+		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
+	}`)
+
+	validSchemaConstraints := []byte(`{
+		"fields": [
+			{
+				"name": "feePercent",
+				"type": "float",
+				"description": "Fee charged per transaction, in percent.",
+				"constraints": {
+					"min": 0,
+					"max": 2.5,
+					"exclusiveMin": true,
+					"multipleOf": 0.25
+				}
+			},
+			{
+				"name": "merchantCode",
+				"type": "string",
+				"description": "Code identifying the merchant.",
+				"constraints": {
+					"minLength": 4,
+					"maxLength": 12,
+					"pattern": "^[A-Z0-9]+$"
+				}
+			},
+			{
+				"name": "port",
+				"type": "int",
+				"description": "Port the gateway listens on.",
+				"default": 443,
+				"constraints": {
+					"enum": ["80", "443"]
+				}
+			}
+		],
+		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
+	}`)
+
+	invalidSchemaPattern := []byte(`{
+		"fields": [
+			{
+				"name": "merchantCode",
+				"type": "string",
+				"description": "Code identifying the merchant.",
+				"constraints": {
+					"pattern": "[A-Z"
+				}
+			}
+		],
+		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
+	}`)
+
+	invalidSchemaType := []byte(`{
+		"fields": [
+			{
+				"name": "retryDelay",
+				"type": "time",
+				"description": "Delay between retries."
+			}
+		],
+		"description": "Configuration schema of the PaymentGateway module in FinanceApp."
+	}`)
+
 	tests := []struct {
 		name        string
 		schemaBytes []byte
 		wantErr     bool
 	}{
 		{"Valid Schema", validSchema, false},
+		{"Valid Schema: Rich types", validSchemaRichTypes, false},
+		{"Invalid Schema: Unknown type", invalidSchemaType, true},
+		{"Valid Schema: Extended constraints", validSchemaConstraints, false},
+		{"Invalid Schema: Invalid pattern", invalidSchemaPattern, true},
+		{"Valid Schema: Default and required", validSchemaDefault, false},
+		{"Invalid Schema: Default out of range", invalidSchemaDefault, true},
 		{"Invalid Schema: No description", invalidSchemaNoDescription, true},
 		{"Invalid Schema: Invalid constraint", invalidSchemaConstraint, true},
 	}
@@ -14,11 +14,17 @@ const RigelSchemaJSON = `{
           },
           "type": {
             "type": "string",
-            "enum": ["int", "float", "string", "bool"]
+            "enum": ["int", "float", "string", "bool", "duration", "[]string", "[]int", "map[string]string", "json", "url", "secret", "flag"]
           },
           "description": {
             "type": "string"
           },
+          "default": {
+            "type": ["string", "number", "boolean", "array", "object"]
+          },
+          "required": {
+            "type": "boolean"
+          },
           "constraints": {
             "type": "object",
             "properties": {
@@ -28,6 +34,28 @@ const RigelSchemaJSON = `{
               "max": {
                 "type": "number"
               },
+              "exclusiveMin": {
+                "type": "boolean"
+              },
+              "exclusiveMax": {
+                "type": "boolean"
+              },
+              "minLength": {
+                "type": "integer",
+                "minimum": 0
+              },
+              "maxLength": {
+                "type": "integer",
+                "minimum": 0
+              },
+              "pattern": {
+                "type": "string",
+                "format": "regex"
+              },
+              "multipleOf": {
+                "type": "number",
+                "exclusiveMinimum": 0
+              },
               "enum": {
                 "type": "array",
                 "items": {
@@ -16,6 +16,10 @@ const dialTimeout = 5 * time.Second
 // EtcdStorage is implements Rigel's Storage interface using etcd v3 client.
 type EtcdStorage struct {
 	Client *clientv3.Client
+
+	// OnWatchError, if set, is called from the watch goroutine whenever a watch hits an error.
+	// The watch keeps retrying after reporting, so errors are informational.
+	OnWatchError func(key string, err error)
 }
 
 var _ types.Storage = &EtcdStorage{}
@@ -84,6 +88,35 @@ func (e *EtcdStorage) Get(ctx context.Context, key string) (string, error) {
 	return value, nil
 }
 
+// GetWithRevision retrieves a value and its mod revision from etcd based on the provided key.
+// If the key does not exist in etcd, the function returns an empty value, a mod revision of 0 and no error.
+func (e *EtcdStorage) GetWithRevision(ctx context.Context, key string) (types.KeyValue, error) {
+	resp, err := e.Client.Get(ctx, key)
+	if err != nil {
+		return types.KeyValue{}, fmt.Errorf("failed to get key from etcd: %w", err)
+	}
+
+	var kv types.KeyValue
+	for _, ev := range resp.Kvs {
+		kv = types.KeyValue{Value: string(ev.Value), ModRevision: ev.ModRevision}
+	}
+	return kv, nil
+}
+
+// GetWithPrefixAndRevision retrieves all keys from etcd that start with the provided prefix, with their values and mod revisions.
+func (e *EtcdStorage) GetWithPrefixAndRevision(ctx context.Context, prefix string) (map[string]types.KeyValue, error) {
+	resp, err := e.Client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keys from etcd: %w", err)
+	}
+	keyVal := make(map[string]types.KeyValue)
+	for _, ev := range resp.Kvs {
+		keyVal[string(ev.Key)] = types.KeyValue{Value: string(ev.Value), ModRevision: ev.ModRevision}
+	}
+
+	return keyVal, nil
+}
+
 // GetWithPrefix retrieves all key-value pairs from etcd where the keys start with the provided prefix.
 func (e *EtcdStorage) GetWithPrefix(ctx context.Context, prefix string) (map[string]string, error) {
 	resp, err := e.Client.Get(ctx, prefix, clientv3.WithPrefix())
@@ -111,21 +144,95 @@ func (e *EtcdStorage) Put(ctx context.Context, key string, value string) error {
 	return nil
 }
 
+// Delete removes the specified key from etcd.
+// Deleting a key that does not exist is not an error.
+func (e *EtcdStorage) Delete(ctx context.Context, key string) error {
+	_, err := e.Client.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete key from etcd: %w", err)
+	}
+	return nil
+}
+
+// DeleteWithPrefix removes all keys from etcd that start with the provided prefix.
+func (e *EtcdStorage) DeleteWithPrefix(ctx context.Context, prefix string) error {
+	_, err := e.Client.Delete(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to delete keys from etcd: %w", err)
+	}
+	return nil
+}
+
+// Txn applies ops in a single etcd transaction, so they all take effect at the same revision or not at all.
+// Revision checks become compares on the keys' mod revisions; if one fails, the keys are read back in
+// the same transaction to report which one changed.
+// More than types.MaxTxnOps ops are refused before anything is sent to etcd.
+func (e *EtcdStorage) Txn(ctx context.Context, ops []types.Op) error {
+	if len(ops) > types.MaxTxnOps {
+		return &types.TxnTooLargeError{Ops: len(ops)}
+	}
+	etcdOps := make([]clientv3.Op, len(ops))
+	var cmps []clientv3.Cmp
+	var checked []types.Op
+	var reads []clientv3.Op
+	for i, op := range ops {
+		if op.Delete {
+			etcdOps[i] = clientv3.OpDelete(op.Key)
+		} else {
+			etcdOps[i] = clientv3.OpPut(op.Key, op.Value)
+		}
+		if op.CheckRevision {
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(op.Key), "=", op.ModRevision))
+			checked = append(checked, op)
+			reads = append(reads, clientv3.OpGet(op.Key))
+		}
+	}
+
+	resp, err := e.Client.Txn(ctx).If(cmps...).Then(etcdOps...).Else(reads...).Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction to etcd: %w", err)
+	}
+	if resp.Succeeded {
+		return nil
+	}
+
+	for i, r := range resp.Responses {
+		var current int64
+		if kvs := r.GetResponseRange().Kvs; len(kvs) > 0 {
+			current = kvs[0].ModRevision
+		}
+		if current != checked[i].ModRevision {
+			return &types.ConflictError{Key: checked[i].Key, ExpectedRevision: checked[i].ModRevision, ModRevision: current}
+		}
+	}
+	// The key changed and changed back between the transaction and the read; report the first checked key
+	return &types.ConflictError{Key: checked[0].Key, ExpectedRevision: checked[0].ModRevision}
+}
+
 // Watch starts watching for changes to a key or a range of keys in etcd and sends the events to the provided channel.
 // If the key is a prefix that matches multiple keys, it watches all those keys.
+// Each event carries its type, the mod revision and the value the key had before the change.
+//
+// The watch survives transient failures: it is re-established from the last revision it delivered,
+// so no change is lost or repeated. If etcd has compacted that revision away, the watch falls back
+// to a full resync of the prefix and sends the events needed to bring the caller up to date.
+// Errors are reported to OnWatchError, if set. When ctx is cancelled or the client is closed,
+// the events channel is closed.
 // key: The key to watch for changes
 // events is the channel to send events when the key's value changes
 func (e *EtcdStorage) Watch(ctx context.Context, key string, events chan<- types.Event) error {
-	watchChan := e.Client.Watch(ctx, key, clientv3.WithPrefix())
-	go func() {
-		for watchResp := range watchChan {
-			for _, event := range watchResp.Events {
-				events <- types.Event{
-					Key:   string(event.Kv.Key),
-					Value: string(event.Kv.Value),
-				}
-			}
-		}
-	}()
+	w := &watcher{
+		storage:  e,
+		key:      key,
+		events:   events,
+		snapshot: make(map[string]string),
+	}
+
+	// Load the current state so that the watch starts from a known revision
+	if err := w.resync(ctx, false); err != nil {
+		return err
+	}
+
+	go w.run(ctx)
 	return nil
 }
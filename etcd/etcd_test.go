@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/remiges-tech/rigel/storagetest"
 	"github.com/remiges-tech/rigel/types"
 	"go.etcd.io/etcd/tests/v3/integration"
 )
@@ -50,26 +51,15 @@ func TestEtcdStorage_Watch(t *testing.T) {
 	// Create a channel for events
 	events := make(chan types.Event)
 
-	// Create a channel for errors
-	errs := make(chan error)
-
-	// Start watching a key
-	go func() {
-		err := etcdStorage.Watch(context.Background(), "test-key", events)
-		if err != nil {
-			errs <- err
-		}
-	}()
-
-	// Check for any errors
-	select {
-	case err := <-errs:
+	// Start watching a key. Watch returns once the starting revision is fixed,
+	// so the put below is guaranteed to be observed.
+	err := etcdStorage.Watch(context.Background(), "test-key", events)
+	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
-	default:
 	}
 
 	// Put a value to the key
-	err := etcdStorage.Put(context.Background(), "test-key", "test-value")
+	err = etcdStorage.Put(context.Background(), "test-key", "test-value")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -142,3 +132,18 @@ func TestGetWithPrefix(t *testing.T) {
 		t.Errorf("Did not expect to find key '%skey2'", prefixV2)
 	}
 }
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) types.Storage {
+		// Setup the test environment
+		integration.BeforeTestExternal(t)
+
+		// Create an embedded etcd server for testing
+		clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+		t.Cleanup(func() { clus.Terminate(t) })
+
+		return &EtcdStorage{
+			Client: clus.RandClient(),
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	minWatchBackoff = 100 * time.Millisecond
+	maxWatchBackoff = 5 * time.Second
+)
+
+// errWatchClosed is reported when etcd closes a watch channel without an error,
+// for example when the connection to the cluster is lost.
+var errWatchClosed = errors.New("etcd watch channel closed")
+
+// watcher holds the state of a single Watch call.
+// snapshot mirrors the watched prefix as of revision rev, the last revision delivered to the caller.
+type watcher struct {
+	storage  *EtcdStorage
+	key      string
+	events   chan<- types.Event
+	snapshot map[string]string
+	rev      int64
+}
+
+// run keeps the watch alive until ctx is cancelled or the client is closed,
+// then closes the events channel.
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.events)
+
+	backoff := minWatchBackoff
+	for {
+		startRev := w.rev
+		err := w.watch(ctx)
+		if ctx.Err() != nil || w.storage.Client.Ctx().Err() != nil {
+			return
+		}
+		w.report(err)
+
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			// The revision to resume from is gone; reload the prefix instead
+			if err := w.resync(ctx, true); err != nil {
+				w.report(err)
+			} else {
+				backoff = minWatchBackoff
+				continue
+			}
+		}
+
+		// A watch that made progress before failing starts over with a short delay
+		if w.rev > startRev {
+			backoff = minWatchBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+// watch runs a single etcd watch starting right after the last delivered revision.
+// It returns when the watch fails or ctx is cancelled.
+func (w *watcher) watch(ctx context.Context) error {
+	// WithRequireLeader makes etcd cancel the watch if the member loses its leader,
+	// instead of leaving it silently stalled
+	wctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	watchChan := w.storage.Client.Watch(wctx, w.key, clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(w.rev+1))
+	for watchResp := range watchChan {
+		if err := watchResp.Err(); err != nil {
+			return err
+		}
+		for _, event := range watchResp.Events {
+			if !w.send(ctx, toEvent(event)) {
+				return ctx.Err()
+			}
+		}
+	}
+	return errWatchClosed
+}
+
+// resync loads the current state of the watched prefix and restarts from its revision.
+// If notify is true, events are sent for every key that changed since the last snapshot.
+func (w *watcher) resync(ctx context.Context, notify bool) error {
+	resp, err := w.storage.Client.Get(ctx, w.key, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to resync watch on %s: %w", w.key, err)
+	}
+	rev := resp.Header.Revision
+
+	current := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		current[string(kv.Key)] = string(kv.Value)
+	}
+
+	if notify {
+		for _, event := range diffSnapshots(w.snapshot, current, rev) {
+			if !w.send(ctx, event) {
+				return ctx.Err()
+			}
+		}
+	}
+
+	w.snapshot = current
+	w.rev = rev
+	return nil
+}
+
+// send delivers an event to the caller and records it in the snapshot.
+// It returns false if ctx was cancelled before the event could be delivered.
+func (w *watcher) send(ctx context.Context, event types.Event) bool {
+	select {
+	case w.events <- event:
+	case <-ctx.Done():
+		return false
+	}
+
+	if event.Type == types.EventDelete {
+		delete(w.snapshot, event.Key)
+	} else {
+		w.snapshot[event.Key] = event.Value
+	}
+	w.rev = event.ModRevision
+	return true
+}
+
+// report passes err to the storage's OnWatchError callback, if one is set.
+func (w *watcher) report(err error) {
+	if w.storage.OnWatchError != nil {
+		w.storage.OnWatchError(w.key, err)
+	}
+}
+
+// toEvent converts an etcd watch event to a Rigel event.
+func toEvent(event *clientv3.Event) types.Event {
+	ev := types.Event{
+		Type:        types.EventPut,
+		Key:         string(event.Kv.Key),
+		Value:       string(event.Kv.Value),
+		ModRevision: event.Kv.ModRevision,
+	}
+	if event.Type == clientv3.EventTypeDelete {
+		ev.Type = types.EventDelete
+		ev.Value = ""
+	}
+	if event.PrevKv != nil {
+		ev.PrevValue = string(event.PrevKv.Value)
+	}
+	return ev
+}
+
+// diffSnapshots returns the events that turn the old snapshot into the new one, in key order.
+func diffSnapshots(old, new map[string]string, rev int64) []types.Event {
+	var events []types.Event
+	for key, value := range new {
+		prev, existed := old[key]
+		if existed && prev == value {
+			continue
+		}
+		events = append(events, types.Event{Type: types.EventPut, Key: key, Value: value, PrevValue: prev, ModRevision: rev})
+	}
+	for key, prev := range old {
+		if _, exists := new[key]; !exists {
+			events = append(events, types.Event{Type: types.EventDelete, Key: key, PrevValue: prev, ModRevision: rev})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+	return events
+}
@@ -0,0 +1,108 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/tests/v3/integration"
+)
+
+func TestWatchResyncsAfterCompaction(t *testing.T) {
+	// Setup the test environment
+	integration.BeforeTestExternal(t)
+
+	// Create an embedded etcd server for testing
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reported []error
+	etcdStorage := &EtcdStorage{
+		Client: clus.RandClient(),
+		OnWatchError: func(key string, err error) {
+			reported = append(reported, err)
+		},
+	}
+
+	prefix := "/remiges/rigel/app/module/1/config/prod/"
+	mustPut := func(key, value string) {
+		if err := etcdStorage.Put(ctx, prefix+key, value); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	mustPut("kept", "same")
+	mustPut("changed", "old")
+	mustPut("removed", "gone")
+
+	// Take the snapshot a watcher would have had at this point
+	events := make(chan types.Event)
+	w := &watcher{storage: etcdStorage, key: prefix, events: events, snapshot: make(map[string]string)}
+	if err := w.resync(ctx, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Change the prefix while the watcher is "disconnected", then compact the history away
+	mustPut("changed", "new")
+	mustPut("added", "fresh")
+	if err := etcdStorage.Delete(ctx, prefix+"removed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp, err := etcdStorage.Client.Get(ctx, prefix)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := etcdStorage.Client.Compact(ctx, resp.Header.Revision); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	go w.run(ctx)
+
+	expected := []types.Event{
+		{Type: types.EventPut, Key: prefix + "added", Value: "fresh"},
+		{Type: types.EventPut, Key: prefix + "changed", Value: "new", PrevValue: "old"},
+		{Type: types.EventDelete, Key: prefix + "removed", PrevValue: "gone"},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-events:
+			got.ModRevision = 0
+			if got != want {
+				t.Errorf("Expected event %+v, got %+v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected to receive event %+v, but didn't", want)
+		}
+	}
+
+	// After the resync the watch resumes normally
+	mustPut("kept", "updated")
+	select {
+	case got := <-events:
+		if got.Key != prefix+"kept" || got.Value != "updated" || got.PrevValue != "same" {
+			t.Errorf("Expected update of 'kept', got %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected to receive an event after resync, but didn't")
+	}
+
+	if len(reported) == 0 || !errors.Is(reported[0], rpctypes.ErrCompacted) {
+		t.Errorf("Expected compaction error to be reported, got %v", reported)
+	}
+
+	// Cancelling the context closes the events channel
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("Expected the events channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the events channel to be closed after cancel")
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/remiges-tech/rigel"
 	"github.com/remiges-tech/rigel/etcd"
@@ -20,6 +19,16 @@ func main() {
 	// Create a new Rigel instance
 	r := rigel.New(etcdStorage, "testapp", "testmodule", 1, "testconfig")
 
+	// Print the value whenever maxAge changes. The values are typed according to the schema.
+	r.OnChange("maxAge", func(oldValue, newValue any) {
+		fmt.Printf("Config has been updated: maxAge %v -> %v\n", oldValue, newValue)
+	})
+
+	// Report every other change as well
+	r.OnAnyChange(func(key string, oldValue, newValue any) {
+		fmt.Printf("%s changed: %v -> %v\n", key, oldValue, newValue)
+	})
+
 	// Start watching for changes
 	err = r.WatchConfig(context.Background())
 	if err != nil {
@@ -27,20 +36,13 @@ func main() {
 		return
 	}
 
-	// Keep the program running and print the cache contents whenever a change is detected
-	var prevValue string
-	for {
-		fmt.Printf("cache: %v", r.Cache)
-		time.Sleep(time.Second)
-		value, err := r.Get(context.Background(), "maxAge")
-		if err != nil {
-			fmt.Printf("Failed to get value: %v\n", err)
-			continue
-		}
-		fmt.Printf("Current value: %s\n", value)
-		if value != prevValue {
-			fmt.Printf("Config has been updated: %s\n", value)
-			prevValue = value
-		}
+	value, err := r.Get(context.Background(), "maxAge")
+	if err != nil {
+		fmt.Printf("Failed to get value: %v\n", err)
+		return
 	}
+	fmt.Printf("Current value: %s\n", value)
+
+	// Keep the program running; the handlers above are called as changes arrive
+	select {}
 }
@@ -0,0 +1,337 @@
+package rigel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigDocumentKind identifies a ConfigDocument and the version of its format.
+const ConfigDocumentKind = "rigel/config/v1"
+
+// Formats a ConfigDocument can be written in.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ConfigDocument is a named config as a self-describing document, written by ExportConfig and read
+// by ImportConfig. It can be moved between clusters or kept as a backup. Values of secret fields are
+// included as they are, so documents must be kept as safe as the config itself.
+type ConfigDocument struct {
+	Kind        string            `json:"kind" yaml:"kind"`
+	App         string            `json:"app" yaml:"app"`
+	Module      string            `json:"module" yaml:"module"`
+	Version     int               `json:"ver" yaml:"ver"`
+	Config      string            `json:"config" yaml:"config"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Parent      string            `json:"parent,omitempty" yaml:"parent,omitempty"` // Parent is the config the config inherits from, under the same version
+	Values      map[string]string `json:"values" yaml:"values"`
+}
+
+// Marshal writes the document in the given format, FormatJSON or FormatYAML.
+func (d *ConfigDocument) Marshal(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON, "":
+		return json.MarshalIndent(d, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(d)
+	default:
+		return nil, fmt.Errorf("unknown document format %q", format)
+	}
+}
+
+// ParseConfigDocument reads a document written by ConfigDocument.Marshal, in either format.
+func ParseConfigDocument(data []byte) (*ConfigDocument, error) {
+	var doc ConfigDocument
+	// JSON is valid YAML, so one parser reads both formats
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config document: %w", err)
+	}
+	if doc.Kind != ConfigDocumentKind {
+		return nil, fmt.Errorf("not a config document: kind is %q, expected %q", doc.Kind, ConfigDocumentKind)
+	}
+	if doc.App == "" || doc.Module == "" || doc.Version == 0 || doc.Config == "" {
+		return nil, fmt.Errorf("config document must name the app, module, version and config")
+	}
+	return &doc, nil
+}
+
+// ExportConfig returns the named config, with its description, parent and values, as a ConfigDocument.
+// The values are the config's own; those it inherits are left to its parent.
+func (r *Rigel) ExportConfig(ctx context.Context) (*ConfigDocument, error) {
+	exists, err := r.ConfigExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("config %s does not exist", r.Config)
+	}
+	prefix := GetConfKeyPath(r.App, r.Module, r.Version, r.Config, "")
+	keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	description, err := r.ConfigDescription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := r.ConfigParent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &ConfigDocument{
+		Kind:        ConfigDocumentKind,
+		App:         r.App,
+		Module:      r.Module,
+		Version:     r.Version,
+		Config:      r.Config,
+		Description: description,
+		Parent:      parent,
+		Values:      make(map[string]string, len(keyVal)),
+	}
+	for key, value := range keyVal {
+		doc.Values[strings.TrimPrefix(key, prefix)] = value
+	}
+	return doc, nil
+}
+
+// ValueChange is the change of a single config key.
+type ValueChange struct {
+	Key string `json:"key"`
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// ConfigDiff describes how a ConfigDocument differs from the named config it is imported into.
+// Values of secret fields are shown as RedactedValue.
+type ConfigDiff struct {
+	Created            bool          `json:"created,omitempty"` // Created is true if the config does not exist yet
+	Added              []ValueChange `json:"added,omitempty"`
+	Changed            []ValueChange `json:"changed,omitempty"`
+	Removed            []ValueChange `json:"removed,omitempty"`
+	DescriptionChanged bool          `json:"description_changed,omitempty"`
+	OldDescription     string        `json:"old_description,omitempty"`
+	NewDescription     string        `json:"new_description,omitempty"`
+	ParentChanged      bool          `json:"parent_changed,omitempty"`
+	OldParent          string        `json:"old_parent,omitempty"`
+	NewParent          string        `json:"new_parent,omitempty"`
+}
+
+// Empty reports whether importing the document changes nothing.
+func (d *ConfigDiff) Empty() bool {
+	return !d.Created && !d.DescriptionChanged && !d.ParentChanged && len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// String returns the differences one per line, marked "+" for a new config and added keys, "-" for
+// removed keys and "~" for changed keys, the description and the parent.
+func (d *ConfigDiff) String() string {
+	var lines []string
+	if d.Created {
+		lines = append(lines, "+ new config")
+	}
+	if d.DescriptionChanged {
+		lines = append(lines, fmt.Sprintf("~ description: %q -> %q", d.OldDescription, d.NewDescription))
+	}
+	if d.ParentChanged {
+		lines = append(lines, fmt.Sprintf("~ parent: %q -> %q", d.OldParent, d.NewParent))
+	}
+	for _, c := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s = %s", c.Key, c.New))
+	}
+	for _, c := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s = %s", c.Key, c.Old))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", c.Key, c.Old, c.New))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PreviewImport validates doc against the schema version it names and returns how importing it
+// would change the stored config, without writing anything.
+func (r *Rigel) PreviewImport(ctx context.Context, doc *ConfigDocument) (*ConfigDiff, error) {
+	diff, _, err := r.prepareImport(ctx, doc)
+	return diff, err
+}
+
+// ImportConfig makes the named config given by doc's app, module, version and config name match the
+// document, creating it if need be. The document is validated against that schema version first, and
+// the values, description and parent are written in a single transaction, removing keys the document
+// does not have. Values the document's parent has count towards the required fields. If the config changes while the import is being prepared, nothing is written and a
+// *ConflictError is returned. ImportConfig returns the changes it made.
+func (r *Rigel) ImportConfig(ctx context.Context, doc *ConfigDocument) (diff *ConfigDiff, err error) {
+	target := r.forDocument(doc)
+	var plan *importPlan
+	var changes []HistoryEntry
+	defer func() {
+		if plan == nil {
+			plan = &importPlan{}
+		}
+		target.auditWrite(ctx, AuditImportConfig, plan.values, plan.deletes, changes, err)
+	}()
+
+	diff, plan, err = r.prepareImport(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Empty() {
+		return diff, nil
+	}
+	changes, err = target.writeValues(ctx, plan.values, plan.deletes, plan.revisions, plan.extra...)
+	if err != nil {
+		return nil, err
+	}
+	// Values inherited from the old parent may be cached
+	if diff.ParentChanged {
+		for _, field := range plan.fields {
+			if _, ok := plan.values[field.Name]; !ok {
+				target.Cache.Delete(GetConfKeyPath(doc.App, doc.Module, doc.Version, doc.Config, field.Name))
+			}
+		}
+	}
+	return diff, nil
+}
+
+// importPlan holds the writes that import a document.
+type importPlan struct {
+	fields    []types.Field
+	values    map[string]string
+	deletes   []string
+	revisions map[string]int64
+	extra     []types.Op
+}
+
+// prepareImport validates doc and works out how it differs from the stored config and what to write.
+func (r *Rigel) prepareImport(ctx context.Context, doc *ConfigDocument) (*ConfigDiff, *importPlan, error) {
+	target := r.forDocument(doc)
+	schemaFields, err := target.getSchemaFields(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+	if err := validateValues(schemaFields, doc.Values); err != nil {
+		return nil, nil, err
+	}
+	// Values the parent has count towards the required fields
+	effective := doc.Values
+	if doc.Parent != "" {
+		chain, err := target.checkParent(ctx, doc.Parent)
+		if err != nil {
+			return nil, nil, err
+		}
+		if effective, err = target.resolvedValues(ctx, chain[1:]); err != nil {
+			return nil, nil, err
+		}
+		for configKey, value := range doc.Values {
+			effective[configKey] = value
+		}
+	}
+	if missing := missingRequiredFields(schemaFields, effective); len(missing) > 0 {
+		return nil, nil, &MissingRequiredFieldsError{Fields: missing}
+	}
+	exists, err := target.ConfigExists(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prefix := GetConfKeyPath(doc.App, doc.Module, doc.Version, doc.Config, "")
+	current, err := r.Storage.GetWithPrefixAndRevision(ctx, prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	descriptionKey := GetConfDescriptionPath(doc.App, doc.Module, doc.Version, doc.Config)
+	description, err := r.Storage.GetWithRevision(ctx, descriptionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config description: %w", err)
+	}
+	parentKey := GetConfParentPath(doc.App, doc.Module, doc.Version, doc.Config)
+	parent, err := r.Storage.GetWithRevision(ctx, parentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config parent: %w", err)
+	}
+
+	redact := func(configKey, value string) string {
+		if field := findField(schemaFields, configKey); field != nil && field.Type == types.TypeSecret {
+			return RedactedValue
+		}
+		return value
+	}
+
+	// Every key is written only if it is still as it was read here
+	diff := &ConfigDiff{Created: !exists}
+	plan := &importPlan{fields: schemaFields, values: make(map[string]string), revisions: make(map[string]int64)}
+	for _, configKey := range sortedKeys(doc.Values) {
+		value := doc.Values[configKey]
+		kv := current[prefix+configKey]
+		switch {
+		case kv.ModRevision == 0:
+			diff.Added = append(diff.Added, ValueChange{Key: configKey, New: redact(configKey, value)})
+		case kv.Value != value:
+			diff.Changed = append(diff.Changed, ValueChange{Key: configKey, Old: redact(configKey, kv.Value), New: redact(configKey, value)})
+		default:
+			continue
+		}
+		plan.values[configKey] = value
+		plan.revisions[configKey] = kv.ModRevision
+	}
+	var removed []string
+	for key := range current {
+		// A key the document sets to an empty value is written, not removed
+		configKey := strings.TrimPrefix(key, prefix)
+		if _, ok := doc.Values[configKey]; !ok {
+			removed = append(removed, configKey)
+		}
+	}
+	sort.Strings(removed)
+	for _, configKey := range removed {
+		kv := current[prefix+configKey]
+		diff.Removed = append(diff.Removed, ValueChange{Key: configKey, Old: redact(configKey, kv.Value)})
+		plan.deletes = append(plan.deletes, configKey)
+		plan.revisions[configKey] = kv.ModRevision
+	}
+
+	if description.Value != doc.Description {
+		diff.DescriptionChanged = true
+		diff.OldDescription = description.Value
+		diff.NewDescription = doc.Description
+		op := types.PutOp(descriptionKey, doc.Description)
+		if doc.Description == "" {
+			op = types.DeleteOp(descriptionKey)
+		}
+		plan.extra = append(plan.extra, op.IfModRevision(description.ModRevision))
+	}
+	if parent.Value != doc.Parent {
+		diff.ParentChanged = true
+		diff.OldParent = parent.Value
+		diff.NewParent = doc.Parent
+		op := types.PutOp(parentKey, doc.Parent)
+		if doc.Parent == "" {
+			op = types.DeleteOp(parentKey)
+		}
+		plan.extra = append(plan.extra, op.IfModRevision(parent.ModRevision))
+	}
+	// A new config is marked as created, so that it exists even without values or metadata
+	if !exists {
+		created := GetConfCreatedPath(doc.App, doc.Module, doc.Version, doc.Config)
+		plan.extra = append(plan.extra, types.PutOp(created, time.Now().UTC().Format(time.RFC3339)).IfModRevision(0))
+	}
+	return diff, plan, nil
+}
+
+// forDocument returns a client for the named config doc describes, sharing r's storage, cache and audit sink.
+func (r *Rigel) forDocument(doc *ConfigDocument) *Rigel {
+	target := r.at(doc.Version, doc.Config)
+	target.App = doc.App
+	target.Module = doc.Module
+	return target
+}
@@ -0,0 +1,160 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestExportImportConfig(t *testing.T) {
+	ctx := context.Background()
+	storage := memstore.New()
+	rigelClient := New(storage, "app", "module", 1, "prod")
+	schema := types.Schema{Version: 1, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString, Required: true},
+		{Name: "port", Type: types.TypeInt},
+		{Name: "debug", Type: types.TypeBool},
+		{Name: "password", Type: types.TypeSecret},
+	}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	values := map[string]string{"host": "example.com", "port": "8080", "debug": "true", "password": "s3cret"}
+	if err := rigelClient.CreateConfig(ctx, "prod", "production", values); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	doc, err := rigelClient.ExportConfig(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if doc.Description != "production" || !reflect.DeepEqual(doc.Values, values) {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+
+	// Both formats read back to the same document
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		b, err := doc.Marshal(format)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		parsed, err := ParseConfigDocument(b)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", format, err)
+		}
+		if !reflect.DeepEqual(parsed, doc) {
+			t.Errorf("%s: expected %+v, got %+v", format, doc, parsed)
+		}
+	}
+	if _, err := ParseConfigDocument([]byte(`{"kind": "other", "app": "app"}`)); err == nil {
+		t.Errorf("Expected a document of another kind to be rejected")
+	}
+
+	// Importing an unchanged document changes nothing
+	diff, err := rigelClient.ImportConfig(ctx, doc)
+	if err != nil || !diff.Empty() {
+		t.Fatalf("Expected no changes, got %v (err %v)", diff, err)
+	}
+
+	// A preview shows the differences, with secrets redacted, without writing them
+	doc.Description = "production EU"
+	doc.Values = map[string]string{"host": "eu.example.com", "port": "8080", "password": "n3w"}
+	diff, err = rigelClient.PreviewImport(ctx, doc)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := &ConfigDiff{
+		Changed:            []ValueChange{{Key: "host", Old: "example.com", New: "eu.example.com"}, {Key: "password", Old: RedactedValue, New: RedactedValue}},
+		Removed:            []ValueChange{{Key: "debug", Old: "true"}},
+		DescriptionChanged: true,
+		OldDescription:     "production",
+		NewDescription:     "production EU",
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("Expected diff\n%s\ngot\n%s", want, diff)
+	}
+	if host, _ := rigelClient.Get(ctx, "host"); host != "example.com" {
+		t.Errorf("Expected a preview not to write anything, got host %q", host)
+	}
+
+	if _, err := rigelClient.ImportConfig(ctx, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	exported, err := rigelClient.ExportConfig(ctx)
+	if err != nil || !reflect.DeepEqual(exported, doc) {
+		t.Errorf("Expected the config to match the imported document, got %+v (err %v)", exported, err)
+	}
+
+	// A key set to an empty value is written, not removed
+	doc.Values["password"] = ""
+	diff, err = rigelClient.ImportConfig(ctx, doc)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := []ValueChange{{Key: "password", Old: RedactedValue, New: RedactedValue}}; !reflect.DeepEqual(diff.Changed, want) || len(diff.Removed) > 0 {
+		t.Errorf("Expected password to be changed, got diff\n%s", diff)
+	}
+
+	// The document is validated against the schema of the version it names
+	doc.Values["port"] = "eighty"
+	var validationErrs ValidationErrors
+	if _, err := rigelClient.ImportConfig(ctx, doc); !errors.As(err, &validationErrs) {
+		t.Errorf("Expected ValidationErrors, got %v", err)
+	}
+}
+
+func TestExportImportConfigWithParent(t *testing.T) {
+	ctx := context.Background()
+	storage := memstore.New()
+	rigelClient := New(storage, "app", "module", 1, "child")
+	schema := types.Schema{Version: 1, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString, Required: true},
+	}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.CreateConfig(ctx, "base", "", map[string]string{"host": "example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.CreateConfig(ctx, "child", "", nil, WithParent("base")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A config without values of its own is exported with its parent
+	doc, err := rigelClient.ExportConfig(ctx)
+	if err != nil {
+		t.Fatalf("Expected an empty config to be exported, got %v", err)
+	}
+	if doc.Parent != "base" || len(doc.Values) != 0 {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+
+	// Imported as a new config, host comes from the parent and the config is marked as created
+	doc.Config = "copy"
+	diff, err := rigelClient.ImportConfig(ctx, doc)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !diff.Created || !diff.ParentChanged || diff.NewParent != "base" {
+		t.Errorf("Expected a new config with parent base, got diff\n%s", diff)
+	}
+	copied := New(storage, "app", "module", 1, "copy")
+	if exists, err := copied.ConfigExists(ctx); err != nil || !exists {
+		t.Errorf("Expected the imported config to exist (err %v)", err)
+	}
+	if host, err := copied.Get(ctx, "host"); err != nil || host != "example.com" {
+		t.Errorf("Expected host to be inherited from base, got %q (err %v)", host, err)
+	}
+
+	// Without the parent, the document lacks a required field
+	doc.Config = "orphan"
+	doc.Parent = ""
+	var missing *MissingRequiredFieldsError
+	if _, err := rigelClient.ImportConfig(ctx, doc); !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingRequiredFieldsError, got %v", err)
+	}
+}
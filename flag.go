@@ -0,0 +1,226 @@
+package rigel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// Flag is the value of a flag field: a feature flag with targeting rules, stored as a JSON object.
+// Evaluate decides whether it is on for a subject:
+//
+//   - a flag that is not enabled is off for everyone;
+//   - it is off for the subjects in Deny, and then on for those in Allow;
+//   - otherwise the first rule whose attribute matches decides;
+//   - and subjects no rule matches fall under Percentage.
+//
+// Without a Percentage, a flag with an allow list or rules is off for the subjects they do not pick,
+// and a flag with neither is on for everyone.
+//
+// Example:
+//
+//	{
+//	  "enabled": true,
+//	  "deny": ["user-13"],
+//	  "allow": ["user-1", "user-2"],
+//	  "rules": [
+//	    {"attribute": "plan", "values": ["beta"]},
+//	    {"attribute": "country", "values": ["IN", "US"], "percentage": 25}
+//	  ],
+//	  "percentage": 0
+//	}
+type Flag struct {
+	Enabled    bool       `json:"enabled"`
+	Deny       []string   `json:"deny,omitempty"`       // Deny lists the IDs of subjects the flag is always off for
+	Allow      []string   `json:"allow,omitempty"`      // Allow lists the IDs of subjects the flag is on for, unless they are denied
+	Rules      []FlagRule `json:"rules,omitempty"`      // Rules are tried in order; the first whose attribute matches decides
+	Percentage *float64   `json:"percentage,omitempty"` // Percentage of the subjects no rule matches the flag is on for
+}
+
+// FlagRule matches the subjects whose attribute has one of the given values.
+type FlagRule struct {
+	Attribute  string   `json:"attribute"`
+	Values     []string `json:"values"`
+	Percentage *float64 `json:"percentage,omitempty"` // Percentage of the matching subjects the flag is on for; all of them if not set
+}
+
+// Subject is who a flag is evaluated for.
+type Subject struct {
+	ID         string            // ID identifies the subject, such as a user or account ID; percentage rollouts hash it
+	Attributes map[string]string // Attributes are matched by the rules of the flag
+}
+
+// ParseFlag reads a flag value and checks its rules.
+func ParseFlag(value string) (*Flag, error) {
+	flag, err := decodeFlag(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := flag.Validate(); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// decodeFlag reads a flag value without checking its rules. Unknown keys are rejected, so that a
+// misspelt rule is not silently ignored.
+func decodeFlag(value string) (Flag, error) {
+	var flag Flag
+	dec := json.NewDecoder(bytes.NewReader([]byte(value)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&flag); err != nil {
+		return Flag{}, fmt.Errorf("failed to convert value to flag: %w", err)
+	}
+	if dec.More() {
+		return Flag{}, fmt.Errorf("failed to convert value to flag: unexpected data after the flag")
+	}
+	return flag, nil
+}
+
+// Validate checks that percentages are between 0 and 100, that every rule names an attribute and
+// the values it matches, and that the allow and deny lists have no empty IDs.
+func (f *Flag) Validate() error {
+	if err := checkPercentage(f.Percentage); err != nil {
+		return err
+	}
+	for _, id := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if id == "" {
+			return fmt.Errorf("allow and deny lists must not have empty subject IDs")
+		}
+	}
+	for i, rule := range f.Rules {
+		if rule.Attribute == "" {
+			return fmt.Errorf("rule %d has no attribute", i+1)
+		}
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("rule %d has no values to match attribute %s against", i+1, rule.Attribute)
+		}
+		if err := checkPercentage(rule.Percentage); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func checkPercentage(percentage *float64) error {
+	if percentage != nil && (*percentage < 0 || *percentage > 100) {
+		return fmt.Errorf("percentage %s is not between 0 and 100", formatNumber(*percentage))
+	}
+	return nil
+}
+
+// Evaluate reports whether the flag named key is on for subject. The key is part of the hash that
+// places subjects in percentage rollouts, so each flag rolls out to a different share of subjects,
+// while a subject stays in or out of a rollout as long as its percentage does not shrink.
+func (f *Flag) Evaluate(key string, subject Subject) bool {
+	if !f.Enabled {
+		return false
+	}
+	for _, id := range f.Deny {
+		if id == subject.ID {
+			return false
+		}
+	}
+	for _, id := range f.Allow {
+		if id == subject.ID {
+			return true
+		}
+	}
+	for _, rule := range f.Rules {
+		value, ok := subject.Attributes[rule.Attribute]
+		if !ok {
+			continue
+		}
+		for _, v := range rule.Values {
+			if v == value {
+				return inRollout(key, subject.ID, rule.Percentage)
+			}
+		}
+	}
+	if f.Percentage == nil && (len(f.Allow) > 0 || len(f.Rules) > 0) {
+		// A targeted flag is only on for the subjects it targets
+		return false
+	}
+	return inRollout(key, subject.ID, f.Percentage)
+}
+
+// inRollout reports whether the subject with the given ID falls within percentage of the subjects of the
+// flag named key. Subjects are spread over 10000 buckets by hash. A subject without an ID is only in a
+// rollout to every subject.
+func inRollout(key, id string, percentage *float64) bool {
+	if percentage == nil || *percentage >= 100 {
+		return true
+	}
+	if *percentage <= 0 || id == "" {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	return float64(h.Sum32()%10000) < *percentage*100
+}
+
+// compiledFlag is a flag value as parsed by IsEnabled, kept until the value changes.
+type compiledFlag struct {
+	value string
+	flag  *Flag
+}
+
+// IsEnabled reports whether the flag field configKey is on for subject. It reads the flag from the
+// client's cache, like Get, and keeps its parsed rules and the schema, so once a flag has been read,
+// checking it does not touch the storage; run WatchConfig to pick up changes to either. A flag that has no value, in the named
+// config, its parents or as the schema default, is off.
+func (r *Rigel) IsEnabled(ctx context.Context, configKey string, subject Subject) (bool, error) {
+	schemaFields, err := r.keptSchemaFields(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get schema: %w", err)
+	}
+	field := findField(schemaFields, configKey)
+	if field == nil {
+		return false, &KeyNotFoundError{Key: configKey}
+	}
+	if field.Type != types.TypeFlag {
+		return false, fmt.Errorf("field %s is a %s, not a %s", configKey, field.Type, types.TypeFlag)
+	}
+
+	key := GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey)
+	value, found := r.Cache.Get(key)
+	if !found {
+		if value, err = r.Get(ctx, configKey); err != nil {
+			return false, err
+		}
+	}
+	if value == "" {
+		return false, nil
+	}
+
+	if c, ok := r.flags.Load(key); ok && c.(*compiledFlag).value == value {
+		return c.(*compiledFlag).flag.Evaluate(configKey, subject), nil
+	}
+	flag, err := ParseFlag(value)
+	if err != nil {
+		return false, fmt.Errorf("flag %s is not valid: %w", configKey, err)
+	}
+	r.flags.Store(key, &compiledFlag{value: value, flag: flag})
+	return flag.Evaluate(configKey, subject), nil
+}
+
+// keptSchemaFields is like getSchemaFields, but keeps the parsed fields until AddSchema or DeleteSchema
+// change them through this client, or WatchConfig sees them change.
+func (r *Rigel) keptSchemaFields(ctx context.Context) ([]types.Field, error) {
+	schemaFieldsKey := GetSchemaFieldsPath(r.App, r.Module, r.Version)
+	if fields, ok := r.schemas.Load(schemaFieldsKey); ok {
+		return fields.([]types.Field), nil
+	}
+	fields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.schemas.Store(schemaFieldsKey, fields)
+	return fields, nil
+}
@@ -0,0 +1,198 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/mocks"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestParseFlag(t *testing.T) {
+	valid := `{"enabled": true, "deny": ["u13"], "allow": ["u1"], "rules": [{"attribute": "plan", "values": ["beta"], "percentage": 12.5}], "percentage": 0}`
+	if _, err := ParseFlag(valid); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	invalid := map[string]string{
+		"not json":           `on`,
+		"unknown key":        `{"enabled": true, "percent": 10}`,
+		"percentage too big": `{"enabled": true, "percentage": 120}`,
+		"negative rule":      `{"enabled": true, "rules": [{"attribute": "plan", "values": ["beta"], "percentage": -1}]}`,
+		"rule no attribute":  `{"enabled": true, "rules": [{"values": ["beta"]}]}`,
+		"rule no values":     `{"enabled": true, "rules": [{"attribute": "plan"}]}`,
+		"empty allow id":     `{"enabled": true, "allow": [""]}`,
+	}
+	for name, value := range invalid {
+		if _, err := ParseFlag(value); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFlagEvaluate(t *testing.T) {
+	percent := func(p float64) *float64 { return &p }
+	flag := &Flag{
+		Enabled: true,
+		Deny:    []string{"u13"},
+		Allow:   []string{"u1", "u13"},
+		Rules: []FlagRule{
+			{Attribute: "plan", Values: []string{"beta"}},
+			{Attribute: "country", Values: []string{"IN"}, Percentage: percent(0)},
+		},
+		Percentage: percent(0),
+	}
+
+	tests := []struct {
+		name    string
+		subject Subject
+		want    bool
+	}{
+		{"allowed", Subject{ID: "u1"}, true},
+		{"deny wins over allow", Subject{ID: "u13"}, false},
+		{"matching rule", Subject{ID: "u2", Attributes: map[string]string{"plan": "beta"}}, true},
+		{"first matching rule decides", Subject{ID: "u2", Attributes: map[string]string{"plan": "beta", "country": "IN"}}, true},
+		{"rule at 0 percent", Subject{ID: "u2", Attributes: map[string]string{"country": "IN"}}, false},
+		{"no rule matches", Subject{ID: "u2", Attributes: map[string]string{"plan": "free"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flag.Evaluate("checkout", tt.subject); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Without a percentage, only the targeted subjects get a targeted flag
+	flag.Percentage = nil
+	if flag.Evaluate("checkout", Subject{ID: "u2"}) || !flag.Evaluate("checkout", Subject{ID: "u1"}) {
+		t.Errorf("Expected a targeted flag without a percentage to be on only for targeted subjects")
+	}
+	if untargeted := (&Flag{Enabled: true}); !untargeted.Evaluate("checkout", Subject{ID: "u2"}) {
+		t.Errorf("Expected a flag without targeting to be on for everyone")
+	}
+
+	flag.Enabled = false
+	if flag.Evaluate("checkout", Subject{ID: "u1"}) {
+		t.Errorf("Expected a disabled flag to be off even for allowed subjects")
+	}
+}
+
+func TestFlagRollout(t *testing.T) {
+	flag := &Flag{Enabled: true, Percentage: new(float64)}
+	*flag.Percentage = 30
+
+	on := 0
+	for i := 0; i < 10000; i++ {
+		subject := Subject{ID: fmt.Sprintf("user-%d", i)}
+		enabled := flag.Evaluate("checkout", subject)
+		if enabled {
+			on++
+		}
+		if flag.Evaluate("checkout", subject) != enabled {
+			t.Fatalf("Expected the same answer for the same subject")
+		}
+	}
+	if on < 2700 || on > 3300 {
+		t.Errorf("Expected about 30%% of subjects to be in the rollout, got %d in 10000", on)
+	}
+
+	// Growing the rollout keeps the subjects already in it
+	for i := 0; i < 1000; i++ {
+		subject := Subject{ID: fmt.Sprintf("user-%d", i)}
+		*flag.Percentage = 30
+		before := flag.Evaluate("checkout", subject)
+		*flag.Percentage = 60
+		if before && !flag.Evaluate("checkout", subject) {
+			t.Fatalf("Expected %s to stay in the rollout", subject.ID)
+		}
+	}
+}
+
+func TestIsEnabled(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+	schema := types.Schema{Version: 1, Description: "description", Fields: []types.Field{
+		{Name: "checkout", Type: types.TypeFlag},
+		{Name: "search", Type: types.TypeFlag, Default: map[string]any{"enabled": true}},
+		{Name: "host", Type: types.TypeString},
+	}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Rules are checked when the flag is set
+	var invalid *ValidationError
+	err := rigelClient.Set(ctx, "checkout", `{"enabled": true, "rules": [{"attribute": "plan"}]}`)
+	if !errors.As(err, &invalid) || invalid.Constraint != ConstraintRules {
+		t.Errorf("Expected a rules ValidationError, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "checkout", `{"enabled": true, "allow": ["u1"], "percentage": 0}`); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if enabled, err := rigelClient.IsEnabled(ctx, "checkout", Subject{ID: "u1"}); err != nil || !enabled {
+		t.Errorf("Expected checkout to be on for u1, got %v (err %v)", enabled, err)
+	}
+	if enabled, err := rigelClient.IsEnabled(ctx, "search", Subject{ID: "u2"}); err != nil || !enabled {
+		t.Errorf("Expected search to be on by default, got %v (err %v)", enabled, err)
+	}
+	if _, err := rigelClient.IsEnabled(ctx, "host", Subject{ID: "u1"}); err == nil {
+		t.Errorf("Expected an error for a field that is not a flag")
+	}
+
+	// Once read, flags are evaluated without the storage
+	rigelClient.Storage = &mocks.MockStorage{
+		GetFunc: func(ctx context.Context, key string) (string, error) {
+			t.Errorf("Storage should not be accessed, got a read of %s", key)
+			return "", nil
+		},
+	}
+	if enabled, err := rigelClient.IsEnabled(ctx, "checkout", Subject{ID: "u2"}); err != nil || enabled {
+		t.Errorf("Expected checkout to be off for u2, got %v (err %v)", enabled, err)
+	}
+
+	// A changed value in the cache is picked up
+	rigelClient.Cache.Set(GetConfKeyPath("app", "module", 1, "config", "checkout"), `{"enabled": false}`)
+	if enabled, err := rigelClient.IsEnabled(ctx, "checkout", Subject{ID: "u1"}); err != nil || enabled {
+		t.Errorf("Expected checkout to be off once disabled, got %v (err %v)", enabled, err)
+	}
+}
+
+func TestIsEnabledFollowsSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+	schema := types.Schema{Version: 1, Description: "description", Fields: []types.Field{
+		{Name: "checkout", Type: types.TypeFlag, Default: map[string]any{"enabled": true}},
+	}}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.WatchConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if enabled, err := rigelClient.IsEnabled(ctx, "checkout", Subject{ID: "u1"}); err != nil || !enabled {
+		t.Fatalf("Expected checkout to be on, got %v (err %v)", enabled, err)
+	}
+
+	// Another client replaces the fields; the kept schema is dropped
+	if err := rigelClient.Storage.Put(ctx, GetSchemaFieldsPath("app", "module", 1), `[{"name": "search", "type": "flag"}]`); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var notFound *KeyNotFoundError
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := rigelClient.IsEnabled(ctx, "checkout", Subject{ID: "u1"}); errors.As(err, &notFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected checkout to be gone once the schema changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,314 @@
+package rigel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// maxWriteAttempts is how many times a write is retried when a value changes between reading it
+// for the history and committing the change.
+const maxWriteAttempts = 5
+
+// HistoryEntry records a single change to a config key. Entries are written in the same transaction
+// as the change itself and are never modified afterwards.
+type HistoryEntry struct {
+	Key      string    `json:"key"`
+	OldValue string    `json:"old"`               // OldValue is empty if the key had no value
+	NewValue string    `json:"new"`               // NewValue is empty if the key was deleted
+	Deleted  bool      `json:"deleted,omitempty"` // Deleted is set if the change removed the value
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor,omitempty"` // Actor is the actor set on the context with WithActor
+
+	// Revision is the revision at which the change was made. Pass it to Rollback to restore the
+	// named config to its state right after this change.
+	Revision int64 `json:"-"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that records actor, such as a user name, as the author of the
+// changes made with it. The actor is stored in the history of every key changed with ctx.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor, or an empty string if there is none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// RedactedValue replaces the values of secret fields in the entries returned by History.
+const RedactedValue = "<redacted>"
+
+// History returns the recorded changes of a config key in the named config, oldest first.
+// If configKey is empty, the changes of every key are returned, ordered by revision.
+// Values of secret fields are replaced with RedactedValue.
+func (r *Rigel) History(ctx context.Context, configKey string) ([]HistoryEntry, error) {
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+	entries, err := r.history(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		field := findField(schemaFields, entries[i].Key)
+		if field == nil || field.Type != types.TypeSecret {
+			continue
+		}
+		if entries[i].OldValue != "" {
+			entries[i].OldValue = RedactedValue
+		}
+		if entries[i].NewValue != "" {
+			entries[i].NewValue = RedactedValue
+		}
+	}
+	return entries, nil
+}
+
+// history returns the recorded changes like History, without redacting anything.
+func (r *Rigel) history(ctx context.Context, configKey string) ([]HistoryEntry, error) {
+	if configKey != "" {
+		exists, err := r.KeyExistsInSchema(ctx, configKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if key exists in schema: %w", err)
+		}
+		if !exists {
+			return nil, &KeyNotFoundError{Key: configKey}
+		}
+	}
+
+	// The trailing slash keeps e.g. "port" from matching "portRange"
+	prefix := GetConfHistoryPath(r.App, r.Module, r.Version, r.Config, "")
+	if configKey != "" {
+		prefix = GetConfHistoryPath(r.App, r.Module, r.Version, r.Config, configKey) + "/"
+	}
+	keyVal, err := r.Storage.GetWithPrefixAndRevision(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	entries := make([]HistoryEntry, 0, len(keyVal))
+	for key, kv := range keyVal {
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(kv.Value), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry %s: %w", key, err)
+		}
+		entry.Revision = kv.ModRevision
+		entries = append(entries, entry)
+	}
+	// A restore writes many entries in one transaction, so entries of the same revision are put in
+	// the order they were first recorded in
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Revision != entries[j].Revision {
+			return entries[i].Revision < entries[j].Revision
+		}
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// Rollback restores every key of the named config to the value it had at revision rev, as recorded
+// in the history, and returns the keys it changed. The rollback itself is written in a single
+// transaction and recorded in the history like any other change, so it can be rolled back too.
+//
+// Keys without any recorded change are left alone, so values set before history was kept are not lost.
+// Restored values are validated against the schema, and if any of the keys is changed while the
+// rollback is being prepared, nothing is written and a *ConflictError is returned.
+func (r *Rigel) Rollback(ctx context.Context, rev int64) (changed []string, err error) {
+	values := make(map[string]string)
+	var deletes []string
+	var changes []HistoryEntry
+	defer func() {
+		r.auditWrite(ctx, AuditRollback, values, deletes, changes, err)
+	}()
+
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+	entries, err := r.history(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.Storage.GetWithPrefixAndRevision(ctx, GetConfKeyPath(r.App, r.Module, r.Version, r.Config, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	// The value at rev is the new value of the last change at or before rev or, if the key was
+	// first changed after rev, the old value of that first change
+	target := make(map[string]HistoryEntry)
+	for _, entry := range entries {
+		if _, seen := target[entry.Key]; !seen || entry.Revision <= rev {
+			target[entry.Key] = entry
+		}
+	}
+
+	revisions := make(map[string]int64)
+	for configKey, entry := range target {
+		value := entry.NewValue
+		if entry.Revision > rev {
+			value = entry.OldValue
+		}
+		kv := current[GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey)]
+		if value == kv.Value {
+			continue
+		}
+		revisions[configKey] = kv.ModRevision
+		if value == "" {
+			deletes = append(deletes, configKey)
+		} else {
+			values[configKey] = value
+		}
+	}
+
+	if err := validateValues(schemaFields, values); err != nil {
+		return nil, err
+	}
+	if changes, err = r.writeValues(ctx, values, deletes, revisions); err != nil {
+		return nil, err
+	}
+
+	changed = append(sortedKeys(values), deletes...)
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// writeValues stores values and removes the keys in deletes, all keyed by config key, in a single
+// transaction that also appends an entry to the history of every key it changes.
+// Keys that have an entry in revisions are only changed if their mod revision still matches it;
+// otherwise a *ConflictError is returned. Other keys are written whatever their current value, and
+// the write is retried if one of them changes while it is being prepared.
+// Any extra operations are made in the same transaction; a conflict on one of them is returned
+// as a *ConflictError without retrying. A write that does not fit in one transaction
+// is refused with a *TooManyChangesError.
+// writeValues returns the changes it recorded in the history.
+func (r *Rigel) writeValues(ctx context.Context, values map[string]string, deletes []string, revisions map[string]int64, extra ...types.Op) ([]HistoryEntry, error) {
+	var err error
+	for attempt := 0; attempt < maxWriteAttempts; attempt++ {
+		var ops []types.Op
+		var changes []HistoryEntry
+		ops, changes, err = r.writeOps(ctx, values, deletes, revisions)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, extra...)
+		if len(ops) == 0 {
+			return nil, nil
+		}
+		if len(ops) > types.MaxTxnOps {
+			return nil, &TooManyChangesError{Keys: len(changes), Max: (types.MaxTxnOps - len(extra)) / 2}
+		}
+
+		err = r.Storage.Txn(ctx, ops)
+		if err == nil {
+			for configKey, value := range values {
+				// An empty value is no value, so Get has to look for an inherited or default one
+				if value == "" {
+					r.Cache.Delete(GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey))
+				} else {
+					r.Cache.Set(GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey), value)
+				}
+			}
+			for _, configKey := range deletes {
+				r.Cache.Delete(GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey))
+			}
+			return changes, nil
+		}
+
+		// Retry only if the conflict is on a key the caller did not expect a revision for. The extra
+		// operations carry their own conditions, so a conflict on one of them is final.
+		var stored *types.ConflictError
+		onExtra := errors.As(err, &stored) && hasOp(extra, stored.Key)
+		err = r.setError(err)
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || onExtra {
+			return nil, err
+		}
+		if _, expected := revisions[conflict.Key]; expected {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// hasOp reports whether one of ops is on key.
+func hasOp(ops []types.Op, key string) bool {
+	for _, op := range ops {
+		if op.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOps returns the operations that make the changes described in writeValues, with their history entries.
+func (r *Rigel) writeOps(ctx context.Context, values map[string]string, deletes []string, revisions map[string]int64) ([]types.Op, []HistoryEntry, error) {
+	current, err := r.Storage.GetWithPrefixAndRevision(ctx, GetConfKeyPath(r.App, r.Module, r.Version, r.Config, ""))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	now := time.Now().UTC()
+	actor := ActorFromContext(ctx)
+	var ops []types.Op
+	var changes []HistoryEntry
+	change := func(configKey string, op types.Op, entry HistoryEntry) error {
+		kv := current[op.Key]
+		rev, expected := revisions[configKey]
+		if !expected {
+			rev = kv.ModRevision
+		}
+		if op.Delete && kv.ModRevision == 0 && !expected {
+			// Nothing to delete, and so nothing to record
+			return nil
+		}
+
+		entry.Key = configKey
+		entry.OldValue = kv.Value
+		entry.Time = now
+		entry.Actor = actor
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode history entry: %w", err)
+		}
+		historyKey := GetConfHistoryPath(r.App, r.Module, r.Version, r.Config, configKey) + fmt.Sprintf("/%020d", now.UnixNano())
+
+		// The history entry must be new, so that entries are never overwritten
+		ops = append(ops, op.IfModRevision(rev), types.PutOp(historyKey, string(b)).IfModRevision(0))
+		changes = append(changes, entry)
+		return nil
+	}
+
+	for _, configKey := range sortedKeys(values) {
+		key := GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey)
+		if err := change(configKey, types.PutOp(key, values[configKey]), HistoryEntry{NewValue: values[configKey]}); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, configKey := range deletes {
+		key := GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey)
+		if err := change(configKey, types.DeleteOp(key), HistoryEntry{Deleted: true}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ops, changes, nil
+}
+
+// configKeyOf returns the config key of a storage key under the keys of the named config.
+func (r *Rigel) configKeyOf(key string) string {
+	return strings.TrimPrefix(key, GetConfKeyPath(r.App, r.Module, r.Version, r.Config, ""))
+}
@@ -0,0 +1,167 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := WithActor(context.Background(), "alice")
+	rigelClient := newMemRigel(t, "config")
+
+	if err := rigelClient.Set(ctx, "host", "first.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "port", "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(WithActor(ctx, "bob"), "host", "second.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteKey(ctx, "host"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	entries, err := rigelClient.History(ctx, "host")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 changes to host, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].OldValue != "" || entries[0].NewValue != "first.example.com" || entries[0].Actor != "alice" {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].OldValue != "first.example.com" || entries[1].NewValue != "second.example.com" || entries[1].Actor != "bob" {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+	if !entries[2].Deleted || entries[2].OldValue != "second.example.com" {
+		t.Errorf("Expected the last entry to record the delete, got %+v", entries[2])
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Revision <= entries[i-1].Revision || entries[i].Time.Before(entries[i-1].Time) {
+			t.Errorf("Expected entries in order, got %+v", entries)
+		}
+	}
+
+	all, err := rigelClient.History(ctx, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(all) != 4 || all[1].Key != "port" {
+		t.Errorf("Expected the 4 changes of the config in order, got %+v", all)
+	}
+
+	// Once its values are gone, the config no longer exists, though its history is kept
+	if err := rigelClient.DeleteKey(ctx, "port"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if configs, err := rigelClient.ListConfigs(ctx); err != nil || len(configs) != 0 {
+		t.Errorf("Expected no configs, got %v (err %v)", configs, err)
+	}
+	if err := rigelClient.CreateConfig(ctx, "config", "", map[string]string{"host": "new.example.com"}); err != nil {
+		t.Errorf("Expected the config to be created again, got %v", err)
+	}
+
+	var notFound *KeyNotFoundError
+	if _, err := rigelClient.History(ctx, "unknown"); !errors.As(err, &notFound) {
+		t.Errorf("Expected a KeyNotFoundError, got %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigelWithDefaults(t)
+
+	// A value stored before history was kept has no entries and is left alone
+	timeoutKey := GetConfKeyPath("app", "module", 1, "config", "timeout")
+	if err := rigelClient.Storage.Put(ctx, timeoutKey, "30"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := rigelClient.SetMany(ctx, map[string]string{"host": "first.example.com", "port": "8080"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	entries, err := rigelClient.History(ctx, "host")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one history entry, got %v (err %v)", entries, err)
+	}
+	rev := entries[0].Revision
+
+	if err := rigelClient.Set(ctx, "host", "second.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteKey(ctx, "port"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "debug", "true"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	changed, err := rigelClient.Rollback(ctx, rev)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"debug", "host", "port"}) {
+		t.Errorf("Expected debug, host and port to change, got %v", changed)
+	}
+
+	values, err := rigelClient.Storage.GetWithPrefix(ctx, GetConfKeyPath("app", "module", 1, "config", ""))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := map[string]string{
+		GetConfKeyPath("app", "module", 1, "config", "host"): "first.example.com",
+		GetConfKeyPath("app", "module", 1, "config", "port"): "8080",
+		timeoutKey: "30",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected %v after rollback, got %v", want, values)
+	}
+
+	// The rollback is recorded like any other change
+	entries, err = rigelClient.History(ctx, "host")
+	if err != nil || len(entries) != 3 || entries[2].NewValue != "first.example.com" {
+		t.Errorf("Expected the rollback in the history of host, got %+v (err %v)", entries, err)
+	}
+}
+
+func TestWriteTooManyChanges(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+	schema := types.Schema{Version: 1, Description: "description"}
+	values := make(map[string]string)
+	for i := 0; i < 70; i++ {
+		name := fmt.Sprintf("key%02d", i)
+		schema.Fields = append(schema.Fields, types.Field{Name: name, Type: types.TypeString})
+		values[name] = "value"
+	}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Every key takes a value and a history entry, which is more than one transaction holds
+	var tooMany *TooManyChangesError
+	if err := rigelClient.CreateConfig(ctx, "config", "", values); !errors.As(err, &tooMany) || tooMany.Keys != 70 {
+		t.Fatalf("Expected a TooManyChangesError for 70 keys, got %v", err)
+	}
+	if exists, _ := rigelClient.ConfigExists(ctx); exists {
+		t.Errorf("Expected nothing to be written")
+	}
+
+	for name := range values {
+		if len(values) == tooMany.Max {
+			break
+		}
+		delete(values, name)
+	}
+	if err := rigelClient.SetMany(ctx, values); err != nil {
+		t.Errorf("Expected %d keys to be written at once, got %v", tooMany.Max, err)
+	}
+}
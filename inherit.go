@@ -0,0 +1,415 @@
+package rigel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// maxParentDepth limits how many ancestors a named config may have, parent included.
+const maxParentDepth = 8
+
+// ParentCycleError is returned when making a config the parent of another would make it inherit from itself.
+type ParentCycleError struct {
+	Chain []string // Chain is the config, the parent it was given, and so on back to the config
+}
+
+func (e *ParentCycleError) Error() string {
+	return fmt.Sprintf("config parents would form a cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// ConfigInUseError is returned by DeleteConfig when other named configs inherit from the config.
+type ConfigInUseError struct {
+	Config   string
+	Children []string // Children are the configs that have Config as their parent
+}
+
+func (e *ConfigInUseError) Error() string {
+	return fmt.Sprintf("config %s is the parent of %s", e.Config, strings.Join(e.Children, ", "))
+}
+
+// CreateOption configures a config created by CreateConfig.
+type CreateOption func(*createOptions)
+
+type createOptions struct {
+	parent string
+}
+
+// WithParent makes the created config inherit from the named config parent, under the same schema version.
+// Required fields then only need a value in the new config if the parent does not have one.
+func WithParent(parent string) CreateOption {
+	return func(o *createOptions) {
+		o.parent = parent
+	}
+}
+
+// ConfigParent returns the name of the config the named config inherits from, or "" if it has no parent.
+func (r *Rigel) ConfigParent(ctx context.Context) (string, error) {
+	parent, err := r.Storage.Get(ctx, GetConfParentPath(r.App, r.Module, r.Version, r.Config))
+	if err != nil {
+		return "", fmt.Errorf("failed to get config parent: %w", err)
+	}
+	return parent, nil
+}
+
+// SetConfigParent makes the named config inherit every key it has no value for from the named config
+// parent, under the same schema version. An empty parent removes the parent, so the config only has
+// its own values and the schema defaults. The parent must exist and must not inherit from the config,
+// directly or through its own parents, and the config must still have a value or default for every
+// required field afterwards.
+func (r *Rigel) SetConfigParent(ctx context.Context, parent string) (err error) {
+	record := r.newAuditRecord(ctx, AuditSetParent)
+	defer func() {
+		r.audit(ctx, []AuditRecord{record}, err)
+	}()
+
+	parentKey := GetConfParentPath(r.App, r.Module, r.Version, r.Config)
+	current, err := r.Storage.GetWithRevision(ctx, parentKey)
+	if err != nil {
+		return fmt.Errorf("failed to get config parent: %w", err)
+	}
+	record.OldValue = current.Value
+	record.NewValue = parent
+
+	exists, err := r.ConfigExists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("config %s does not exist", r.Config)
+	}
+	chain, err := r.checkParent(ctx, parent)
+	if err != nil {
+		return err
+	}
+
+	// The config's own values, with those it would inherit, must cover the required fields
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+	values, err := r.resolvedValues(ctx, chain)
+	if err != nil {
+		return err
+	}
+	if missing := missingRequiredFields(schemaFields, values); len(missing) > 0 {
+		return &MissingRequiredFieldsError{Fields: missing}
+	}
+
+	op := types.PutOp(parentKey, parent)
+	if parent == "" {
+		op = types.DeleteOp(parentKey)
+	}
+	if err := r.Storage.Txn(ctx, []types.Op{op.IfModRevision(current.ModRevision)}); err != nil {
+		return fmt.Errorf("failed to set config parent: %w", err)
+	}
+
+	// Values inherited from the old parent may be cached
+	for _, field := range schemaFields {
+		r.Cache.Delete(GetConfKeyPath(r.App, r.Module, r.Version, r.Config, field.Name))
+	}
+	return nil
+}
+
+// checkParent checks that parent can be made the parent of the named config, and returns the chain
+// of configs the named config would then inherit from, starting with the named config itself.
+func (r *Rigel) checkParent(ctx context.Context, parent string) ([]string, error) {
+	if parent == "" {
+		return []string{r.Config}, nil
+	}
+	exists, err := r.at(r.Version, parent).ConfigExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("parent config %s does not exist", parent)
+	}
+	ancestors, err := r.parentChain(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	chain := append([]string{r.Config}, ancestors...)
+	for i, name := range ancestors {
+		if name == r.Config {
+			return nil, &ParentCycleError{Chain: chain[:i+2]}
+		}
+	}
+	if len(chain) > maxParentDepth+1 {
+		return nil, fmt.Errorf("config %s would have more than %d ancestors", r.Config, maxParentDepth)
+	}
+	return chain, nil
+}
+
+// parentChain returns config followed by its parent, its parent's parent and so on. It stops at the
+// first config seen twice, so that a cycle made by concurrent changes cannot make it loop.
+func (r *Rigel) parentChain(ctx context.Context, config string) ([]string, error) {
+	chain := []string{config}
+	seen := map[string]bool{config: true}
+	for len(chain) <= maxParentDepth {
+		parent, err := r.Storage.Get(ctx, GetConfParentPath(r.App, r.Module, r.Version, chain[len(chain)-1]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config parent: %w", err)
+		}
+		if parent == "" || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		chain = append(chain, parent)
+	}
+	return chain, nil
+}
+
+// inheritedValue looks configKey up in each of the configs in ancestors in turn. It returns the first
+// value found and the config it was found in, or two empty strings if none of them has a value.
+func (r *Rigel) inheritedValue(ctx context.Context, ancestors []string, configKey string) (value, config string, err error) {
+	for _, name := range ancestors {
+		value, err := r.Storage.Get(ctx, GetConfKeyPath(r.App, r.Module, r.Version, name, configKey))
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get config value: %w", err)
+		}
+		if value != "" {
+			return value, name, nil
+		}
+	}
+	return "", "", nil
+}
+
+// configLayers returns the values of each config in chain, keyed by config name and then by config key.
+func (r *Rigel) configLayers(ctx context.Context, chain []string) (map[string]map[string]string, error) {
+	layers := make(map[string]map[string]string, len(chain))
+	for _, name := range chain {
+		prefix := GetConfKeyPath(r.App, r.Module, r.Version, name, "")
+		keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config %s: %w", name, err)
+		}
+		layer := make(map[string]string, len(keyVal))
+		for key, value := range keyVal {
+			layer[strings.TrimPrefix(key, prefix)] = value
+		}
+		layers[name] = layer
+	}
+	return layers, nil
+}
+
+// resolvedValues returns the value of every key set in any config in chain, taken from the first config
+// in chain that has one.
+func (r *Rigel) resolvedValues(ctx context.Context, chain []string) (map[string]string, error) {
+	layers, err := r.configLayers(ctx, chain)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[string]string, len(chain))
+	for i := 1; i < len(chain); i++ {
+		parents[chain[i-1]] = chain[i]
+	}
+	return mergeParents(chain[0], layers, parents), nil
+}
+
+// mergeParents returns the values of config together with those it inherits, given the values and the
+// parent of every config, keyed by config name.
+func mergeParents(config string, layers map[string]map[string]string, parents map[string]string) map[string]string {
+	chain := []string{config}
+	seen := map[string]bool{config: true}
+	for parent := parents[config]; parent != "" && !seen[parent] && len(chain) <= maxParentDepth; parent = parents[parent] {
+		seen[parent] = true
+		chain = append(chain, parent)
+	}
+	values := make(map[string]string)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for configKey, value := range layers[chain[i]] {
+			values[configKey] = value
+		}
+	}
+	return values
+}
+
+// ResolvedValue is the effective value of a config key and where it comes from.
+type ResolvedValue struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Config  string `json:"config,omitempty"`  // Config is the named config the value is set in, "" if it is not set in any
+	Default bool   `json:"default,omitempty"` // Default is true if the value is the schema default
+}
+
+// ResolveConfig returns the effective value of every field of the schema, in schema order: the named
+// config's own value, else the value of its nearest ancestor that has one, else the schema default.
+// Fields with none of these have an empty Value. Values of secret fields are replaced with RedactedValue.
+func (r *Rigel) ResolveConfig(ctx context.Context) ([]ResolvedValue, error) {
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+	exists, err := r.ConfigExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("config %s does not exist", r.Config)
+	}
+	chain, err := r.parentChain(ctx, r.Config)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := r.configLayers(ctx, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]ResolvedValue, 0, len(schemaFields))
+	for i := range schemaFields {
+		field := &schemaFields[i]
+		rv := ResolvedValue{Key: field.Name}
+		for _, name := range chain {
+			if value := layers[name][field.Name]; value != "" {
+				rv.Value, rv.Config = value, name
+				break
+			}
+		}
+		if rv.Config == "" {
+			rv.Value, rv.Default = DefaultValue(field)
+		}
+		if field.Type == types.TypeSecret && rv.Value != "" {
+			rv.Value = RedactedValue
+		}
+		resolved = append(resolved, rv)
+	}
+	return resolved, nil
+}
+
+// childConfigs returns the named configs under the schema version that have the named config as their parent.
+func (r *Rigel) childConfigs(ctx context.Context) ([]string, error) {
+	configs, err := r.ListConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var children []string
+	for _, name := range configs {
+		parent, err := r.at(r.Version, name).ConfigParent(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if parent == r.Config {
+			children = append(children, name)
+		}
+	}
+	return children, nil
+}
+
+// inheritedChange is a change of the effective value of a config key. Old or New is "" if the key had,
+// or has, no value in the config or any of its ancestors.
+type inheritedChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// chainWatch follows the effective values of a named config while it is being watched, keeping the
+// values of the config and of each of its ancestors, so that a change to any of them can be turned
+// into changes of the config's effective values.
+type chainWatch struct {
+	r      *Rigel
+	chain  []string
+	layers map[string]map[string]string
+}
+
+// newChainWatch loads the named config and its ancestors.
+func (r *Rigel) newChainWatch(ctx context.Context) (*chainWatch, error) {
+	w := &chainWatch{r: r}
+	if err := w.load(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// watchPrefix is the prefix of every named config under the schema version, which covers any
+// config that is or becomes an ancestor.
+func (r *Rigel) watchPrefix() string {
+	return GetConfPath(r.App, r.Module, r.Version, "")
+}
+
+func (w *chainWatch) load(ctx context.Context) error {
+	chain, err := w.r.parentChain(ctx, w.r.Config)
+	if err != nil {
+		return err
+	}
+	layers, err := w.r.configLayers(ctx, chain)
+	if err != nil {
+		return err
+	}
+	w.chain, w.layers = chain, layers
+	return nil
+}
+
+// value returns the effective value of configKey, not counting the schema default.
+func (w *chainWatch) value(configKey string) string {
+	for _, name := range w.chain {
+		if value := w.layers[name][configKey]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// values returns the effective values of the keys set in the config or any of its ancestors.
+func (w *chainWatch) values() map[string]string {
+	values := make(map[string]string)
+	for _, layer := range w.layers {
+		for configKey := range layer {
+			values[configKey] = w.value(configKey)
+		}
+	}
+	return values
+}
+
+// apply updates the watch with event, a change to a key under watchPrefix, and returns the changes
+// of effective values it makes. A change of the parent of the config or of one of its ancestors
+// reloads the whole chain.
+func (w *chainWatch) apply(ctx context.Context, event types.Event) ([]inheritedChange, error) {
+	rest := strings.TrimPrefix(event.Key, w.r.watchPrefix())
+	name, path, ok := strings.Cut(rest, "/")
+	if !ok || w.layers[name] == nil {
+		return nil, nil
+	}
+	value := event.Value
+	if event.Type == types.EventDelete {
+		value = ""
+	}
+
+	if path == "meta/"+configParentKey {
+		before := w.values()
+		if err := w.load(ctx); err != nil {
+			return nil, err
+		}
+		after := w.values()
+		for configKey := range before {
+			if _, ok := after[configKey]; !ok {
+				after[configKey] = ""
+			}
+		}
+		var changes []inheritedChange
+		for _, configKey := range sortedKeys(after) {
+			if before[configKey] != after[configKey] {
+				changes = append(changes, inheritedChange{Key: configKey, Old: before[configKey], New: after[configKey]})
+			}
+		}
+		return changes, nil
+	}
+
+	configKey, ok := strings.CutPrefix(path, "keys/")
+	if !ok {
+		return nil, nil
+	}
+	before := w.value(configKey)
+	if value == "" {
+		delete(w.layers[name], configKey)
+	} else {
+		w.layers[name][configKey] = value
+	}
+	if after := w.value(configKey); after != before {
+		return []inheritedChange{{Key: configKey, Old: before, New: after}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,210 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+// newInheritingRigel returns a client for config prod-us, which inherits from prod, on a schema with a required host.
+func newInheritingRigel(t *testing.T) *Rigel {
+	t.Helper()
+	ctx := context.Background()
+	base := New(memstore.New(), "app", "module", 1, "prod")
+	schema := types.Schema{Version: 1, Description: "description", Fields: []types.Field{
+		{Name: "host", Type: types.TypeString, Required: true},
+		{Name: "port", Type: types.TypeInt, Default: float64(8080)},
+		{Name: "region", Type: types.TypeString},
+		{Name: "token", Type: types.TypeSecret},
+	}}
+	if err := base.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := base.CreateConfig(ctx, "prod", "", map[string]string{"host": "prod.example.com", "token": "s3cret"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The required host comes from the parent
+	if err := base.CreateConfig(ctx, "prod-us", "", map[string]string{"region": "us"}, WithParent("prod")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return base.at(1, "prod-us")
+}
+
+func TestConfigInheritance(t *testing.T) {
+	ctx := context.Background()
+	child := newInheritingRigel(t)
+	base := child.at(1, "prod")
+
+	if host, err := child.Get(ctx, "host"); err != nil || host != "prod.example.com" {
+		t.Errorf("Expected host to be inherited, got %q (err %v)", host, err)
+	}
+	if port, err := child.GetInt(ctx, "port"); err != nil || port != 8080 {
+		t.Errorf("Expected the default port, got %d (err %v)", port, err)
+	}
+
+	var cfg struct {
+		Host   string `json:"host"`
+		Port   int    `json:"port"`
+		Region string `json:"region"`
+	}
+	if err := child.LoadConfig(ctx, &cfg); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Host != "prod.example.com" || cfg.Port != 8080 || cfg.Region != "us" {
+		t.Errorf("Expected inherited, default and own values, got %+v", cfg)
+	}
+
+	// The child's own value wins over the parent's
+	if err := child.Set(ctx, "host", "us.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resolved, err := child.ResolveConfig(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []ResolvedValue{
+		{Key: "host", Value: "us.example.com", Config: "prod-us"},
+		{Key: "port", Value: "8080", Default: true},
+		{Key: "region", Value: "us", Config: "prod-us"},
+		{Key: "token", Value: RedactedValue, Config: "prod"},
+	}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Errorf("Expected %+v, got %+v", want, resolved)
+	}
+
+	// A parent with children cannot be deleted
+	var inUse *ConfigInUseError
+	if err := base.DeleteConfig(ctx); !errors.As(err, &inUse) || !reflect.DeepEqual(inUse.Children, []string{"prod-us"}) {
+		t.Errorf("Expected a ConfigInUseError naming prod-us, got %v", err)
+	}
+
+	// Cycles are refused
+	var cycle *ParentCycleError
+	if err := base.SetConfigParent(ctx, "prod-us"); !errors.As(err, &cycle) {
+		t.Errorf("Expected a ParentCycleError, got %v", err)
+	}
+	if err := base.SetConfigParent(ctx, "missing"); err == nil {
+		t.Errorf("Expected a missing parent to be refused")
+	}
+
+	// Without the parent, the token is no longer inherited
+	if err := child.SetConfigParent(ctx, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token, err := child.Get(ctx, "token"); err != nil || token != "" {
+		t.Errorf("Expected no token without the parent, got %q (err %v)", token, err)
+	}
+	if parent, _ := child.ConfigParent(ctx); parent != "" {
+		t.Errorf("Expected no parent, got %q", parent)
+	}
+}
+
+func TestSetConfigParentMissingRequired(t *testing.T) {
+	ctx := context.Background()
+	child := newInheritingRigel(t)
+
+	// prod-us has no host of its own, so it cannot lose its parent
+	var missing *MissingRequiredFieldsError
+	if err := child.SetConfigParent(ctx, ""); !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingRequiredFieldsError, got %v", err)
+	}
+	if parent, _ := child.ConfigParent(ctx); parent != "prod" {
+		t.Errorf("Expected the parent to be kept, got %q", parent)
+	}
+}
+
+func TestWatchConfigFollowsParent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	child := newInheritingRigel(t)
+	base := child.at(1, "prod")
+
+	type change struct {
+		key      string
+		old, new any
+	}
+	changes := make(chan change, 10)
+	child.OnAnyChange(func(key string, oldValue, newValue any) {
+		changes <- change{key, oldValue, newValue}
+	})
+	if _, err := child.Get(ctx, "host"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := child.WatchConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectChange := func(want change) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("Expected change %+v, got %+v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected change %+v, but didn't get it", want)
+		}
+	}
+
+	// A change to a value the child inherits is reported, and updates the cache
+	if err := base.Set(ctx, "host", "new.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectChange(change{"host", "prod.example.com", "new.example.com"})
+	if host, _ := child.Cache.Get(GetConfKeyPath("app", "module", 1, "prod-us", "host")); host != "new.example.com" {
+		t.Errorf("Expected the cache to hold the inherited value, got %q", host)
+	}
+
+	// A change the child overrides is not
+	if err := child.Set(ctx, "host", "us.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectChange(change{"host", "new.example.com", "us.example.com"})
+	if err := base.Set(ctx, "host", "other.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Dropping the parent takes away what was inherited
+	if err := child.SetConfigParent(ctx, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectChange(change{"token", "s3cret", nil})
+}
+
+// failingParentStorage fails reads of the parent of a config, and keeps the context of its last watch.
+type failingParentStorage struct {
+	types.Storage
+	watchCtx context.Context
+}
+
+func (s *failingParentStorage) Get(ctx context.Context, key string) (string, error) {
+	if strings.HasSuffix(key, "/meta/"+configParentKey) {
+		return "", errors.New("unavailable")
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *failingParentStorage) Watch(ctx context.Context, key string, events chan<- types.Event) error {
+	s.watchCtx = ctx
+	return s.Storage.Watch(ctx, key, events)
+}
+
+func TestWatchConfigStopsWatchOnError(t *testing.T) {
+	ctx := context.Background()
+	child := newInheritingRigel(t)
+	storage := &failingParentStorage{Storage: child.Storage}
+	child.Storage = storage
+
+	if err := child.WatchConfig(ctx); err == nil {
+		t.Fatalf("Expected an error when the parents cannot be loaded")
+	}
+	if storage.watchCtx == nil || storage.watchCtx.Err() == nil {
+		t.Errorf("Expected the storage watch to be stopped")
+	}
+}
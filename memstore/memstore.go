@@ -0,0 +1,311 @@
+// Package memstore provides an in-memory implementation of the Storage interface defined in the Rigel project.
+// It is intended for tests and local development, where running an etcd process is not practical.
+// Data lives only as long as the MemStorage value and is not shared between processes.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// MemStorage implements Rigel's Storage interface using an in-memory map.
+// It is safe for concurrent use.
+type MemStorage struct {
+	data     map[string]types.KeyValue
+	watchers map[*watcher]struct{}
+	revision int64 // revision is incremented on every change, like etcd's store revision
+	mu       sync.RWMutex
+}
+
+var _ types.Storage = &MemStorage{}
+
+// watcher holds the state of a single Watch call. Events are queued by the writer
+// and forwarded to the caller's channel by a dedicated goroutine, so a slow reader
+// never blocks Put and events are delivered in the order they happened.
+type watcher struct {
+	prefix string
+	queue  []types.Event
+	notify chan struct{}
+	mu     sync.Mutex
+}
+
+// New creates a new, empty instance of MemStorage.
+func New() *MemStorage {
+	return &MemStorage{
+		data:     make(map[string]types.KeyValue),
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// Get retrieves a value based on the provided key.
+// If the key does not exist, the function returns an empty string and no error.
+func (m *MemStorage) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.data[key].Value, nil
+}
+
+// GetWithRevision retrieves a value and its mod revision based on the provided key.
+// If the key does not exist, the function returns an empty value, a mod revision of 0 and no error.
+func (m *MemStorage) GetWithRevision(ctx context.Context, key string) (types.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return types.KeyValue{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.data[key], nil
+}
+
+// GetWithPrefix retrieves all key-value pairs where the keys start with the provided prefix.
+func (m *MemStorage) GetWithPrefix(ctx context.Context, prefix string) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keyVal := make(map[string]string)
+	for k, kv := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keyVal[k] = kv.Value
+		}
+	}
+	return keyVal, nil
+}
+
+// GetWithPrefixAndRevision retrieves all keys that start with the provided prefix, with their values and mod revisions.
+func (m *MemStorage) GetWithPrefixAndRevision(ctx context.Context, prefix string) (map[string]types.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keyVal := make(map[string]types.KeyValue)
+	for k, kv := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keyVal[k] = kv
+		}
+	}
+	return keyVal, nil
+}
+
+// Put stores a value at the specified key. If the key already exists,
+// its value is updated with the new value. Every watcher whose prefix
+// matches the key is notified of the change.
+func (m *MemStorage) Put(ctx context.Context, key string, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revision++
+	m.put(key, value)
+	return nil
+}
+
+// Delete removes the specified key. Deleting a key that does not exist is not an error.
+// Watchers are notified only if the key existed.
+func (m *MemStorage) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[key]; ok {
+		m.revision++
+		m.delete(key)
+	}
+	return nil
+}
+
+// DeleteWithPrefix removes all keys that start with the provided prefix.
+func (m *MemStorage) DeleteWithPrefix(ctx context.Context, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// As in etcd, all keys removed by one call share a single revision
+	keys := m.sortedKeys(prefix)
+	if len(keys) > 0 {
+		m.revision++
+	}
+	for _, k := range keys {
+		m.delete(k)
+	}
+	return nil
+}
+
+// Txn applies ops atomically. As in etcd, all changes share a single revision, and a key may appear only once.
+// If the mod revision of a key checked by an Op has changed, nothing is applied and a *types.ConflictError is returned.
+func (m *MemStorage) Txn(ctx context.Context, ops []types.Op) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(ops) > types.MaxTxnOps {
+		return &types.TxnTooLargeError{Ops: len(ops)}
+	}
+
+	seen := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		if seen[op.Key] {
+			return fmt.Errorf("duplicate key %s in transaction", op.Key)
+		}
+		seen[op.Key] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, op := range ops {
+		if current := m.data[op.Key].ModRevision; op.CheckRevision && current != op.ModRevision {
+			return &types.ConflictError{Key: op.Key, ExpectedRevision: op.ModRevision, ModRevision: current}
+		}
+	}
+
+	changed := false
+	for _, op := range ops {
+		if _, ok := m.data[op.Key]; !op.Delete || ok {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	m.revision++
+	for _, op := range ops {
+		if op.Delete {
+			if _, ok := m.data[op.Key]; ok {
+				m.delete(op.Key)
+			}
+			continue
+		}
+		m.put(op.Key, op.Value)
+	}
+	return nil
+}
+
+// Watch starts watching for changes to a key or a range of keys and sends the events to the provided channel.
+// As with the etcd implementation, the key is treated as a prefix, so it watches all keys that start with it.
+// The watch stops when ctx is cancelled, after which the events channel is closed.
+func (m *MemStorage) Watch(ctx context.Context, key string, events chan<- types.Event) error {
+	w := &watcher{
+		prefix: key,
+		notify: make(chan struct{}, 1),
+	}
+
+	m.mu.Lock()
+	m.watchers[w] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.watchers, w)
+			m.mu.Unlock()
+			close(events)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.notify:
+			}
+
+			for _, event := range w.drain() {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// put stores a value and notifies watchers using the current revision.
+// The caller must hold m.mu.
+func (m *MemStorage) put(key, value string) {
+	prev := m.data[key].Value
+	m.data[key] = types.KeyValue{Value: value, ModRevision: m.revision}
+	m.publish(types.Event{
+		Type:        types.EventPut,
+		Key:         key,
+		Value:       value,
+		PrevValue:   prev,
+		ModRevision: m.revision,
+	})
+}
+
+// delete removes a key and notifies watchers using the current revision.
+// The caller must hold m.mu and must have checked that the key exists.
+func (m *MemStorage) delete(key string) {
+	prev := m.data[key].Value
+	delete(m.data, key)
+	m.publish(types.Event{
+		Type:        types.EventDelete,
+		Key:         key,
+		PrevValue:   prev,
+		ModRevision: m.revision,
+	})
+}
+
+// sortedKeys returns the keys that start with prefix in lexical order, which is
+// the order etcd reports them in. The caller must hold m.mu.
+func (m *MemStorage) sortedKeys(prefix string) []string {
+	var keys []string
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// publish queues the event on every watcher whose prefix matches the event key.
+// The caller must hold m.mu.
+func (m *MemStorage) publish(event types.Event) {
+	for w := range m.watchers {
+		if !strings.HasPrefix(event.Key, w.prefix) {
+			continue
+		}
+		w.mu.Lock()
+		w.queue = append(w.queue, event)
+		w.mu.Unlock()
+
+		// Wake up the forwarding goroutine without blocking if it is already signalled
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// drain returns the queued events and empties the queue.
+func (w *watcher) drain() []types.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := w.queue
+	w.queue = nil
+	return events
+}
@@ -0,0 +1,47 @@
+package memstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/rigel/storagetest"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) types.Storage {
+		return New()
+	})
+}
+
+func TestWatchStopsOnCancel(t *testing.T) {
+	m := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan types.Event)
+	if err := m.Watch(ctx, "/key", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cancel()
+
+	// The watcher unregisters itself once it sees the cancellation
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		m.mu.RLock()
+		n := len(m.watchers)
+		m.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected watcher to be removed after cancel, %d still registered", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Put must not block even though nobody reads the events channel
+	if err := m.Put(context.Background(), "/key", "value"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
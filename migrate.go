@@ -0,0 +1,266 @@
+package rigel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// MigrationReport describes what MigrateConfig does, or with DryRun would do, to move a named config
+// from one schema version to another.
+type MigrationReport struct {
+	Config    string            `json:"config"`
+	From      int               `json:"from"`
+	To        int               `json:"to"`
+	Copied    []string          `json:"copied"`    // Copied are the keys copied under the same name
+	Renamed   map[string]string `json:"renamed"`   // Renamed maps old key names to their new names
+	Defaulted []string          `json:"defaulted"` // Defaulted are the new keys filled from their default
+	Dropped   []string          `json:"dropped"`   // Dropped are the keys the target schema has no field for
+	Values    map[string]string `json:"values"`    // Values are the values of the migrated config, keyed by config key
+	DryRun    bool              `json:"dry_run"`
+
+	// Parent is the config the migrated config inherits from, "" if it has none
+	Parent string `json:"parent,omitempty"`
+}
+
+// String returns the report one key per line: "=" for copied keys, ">" for renamed keys,
+// "+" for keys filled from their default and "-" for dropped keys.
+func (m *MigrationReport) String() string {
+	var lines []string
+	for _, key := range m.Copied {
+		lines = append(lines, fmt.Sprintf("= %s", key))
+	}
+	for _, old := range sortedKeys(m.Renamed) {
+		lines = append(lines, fmt.Sprintf("> %s -> %s", old, m.Renamed[old]))
+	}
+	for _, key := range m.Defaulted {
+		lines = append(lines, fmt.Sprintf("+ %s = %s", key, m.Values[key]))
+	}
+	for _, key := range m.Dropped {
+		lines = append(lines, fmt.Sprintf("- %s", key))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MigrateOption configures MigrateConfig.
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	renames map[string]string
+	dryRun  bool
+}
+
+// RenameFields makes MigrateConfig copy the value of each old field name in renames to the new name it maps to.
+func RenameFields(renames map[string]string) MigrateOption {
+	return func(o *migrateOptions) {
+		o.renames = renames
+	}
+}
+
+// DryRun makes MigrateConfig only report what it would do, without writing anything.
+func DryRun() MigrateOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+// LoadRenames reads a field rename mapping for RenameFields from a JSON file holding an object
+// that maps old field names to new ones, such as {"timeout_secs": "timeout"}.
+func LoadRenames(file string) (map[string]string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rename mapping: %w", err)
+	}
+	var renames map[string]string
+	if err := json.Unmarshal(b, &renames); err != nil {
+		return nil, fmt.Errorf("failed to parse rename mapping: %w", err)
+	}
+	return renames, nil
+}
+
+// MigrateConfig copies the named config from schema version from to schema version to. Keys the
+// target schema has a field of the same name for are copied, keys named in RenameFields are copied
+// under their new name, and fields of the target schema left without a value are filled from their
+// default. Keys the target schema has no field for are dropped. The source config is left alone.
+// A config with a parent keeps it, so the parent must be migrated first; fields the config inherits
+// under the target version are not filled from their default.
+//
+// Every value is validated against the target schema, and the migrated config is written in a single
+// transaction, together with the config's description and parent. MigrateConfig fails with a
+// *ConfigExistsError if the config already exists under the target version, and with a
+// *MissingRequiredFieldsError if a required field has neither a value nor a default. The report is
+// returned even if the migration fails, so that it can be shown with the error.
+func (r *Rigel) MigrateConfig(ctx context.Context, from, to int, config string, opts ...MigrateOption) (report *MigrationReport, err error) {
+	var o migrateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	report = &MigrationReport{Config: config, From: from, To: to, Renamed: map[string]string{}, Values: map[string]string{}, DryRun: o.dryRun}
+	target := r.at(to, config)
+	var changes []HistoryEntry
+	defer func() {
+		if !o.dryRun {
+			target.auditWrite(ctx, AuditMigrate, report.Values, nil, changes, err)
+		}
+	}()
+
+	if from == to {
+		return report, fmt.Errorf("cannot migrate config %s to the version it is in", config)
+	}
+	if _, err := r.getSchemaVersion(ctx, from); err != nil {
+		return report, fmt.Errorf("failed to get schema version %d: %w", from, err)
+	}
+	targetSchema, err := r.getSchemaVersion(ctx, to)
+	if err != nil {
+		return report, fmt.Errorf("failed to get schema version %d: %w", to, err)
+	}
+	for old, renamed := range o.renames {
+		if findField(targetSchema.Fields, renamed) == nil {
+			return report, fmt.Errorf("%s is renamed to %s, which is not in schema version %d", old, renamed, to)
+		}
+	}
+
+	source := r.at(from, config)
+	exists, err := source.ConfigExists(ctx)
+	if err != nil {
+		return report, err
+	}
+	if !exists {
+		return report, fmt.Errorf("config %s does not exist under schema version %d", config, from)
+	}
+	prefix := GetConfKeyPath(r.App, r.Module, from, config, "")
+	keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return report, fmt.Errorf("failed to get config: %w", err)
+	}
+	description, err := source.ConfigDescription(ctx)
+	if err != nil {
+		return report, err
+	}
+	// The parent must have been migrated first, and what it has under the target version is inherited
+	var inherited map[string]string
+	if report.Parent, err = source.ConfigParent(ctx); err != nil {
+		return report, err
+	}
+	if report.Parent != "" {
+		chain, err := target.checkParent(ctx, report.Parent)
+		if err != nil {
+			return report, fmt.Errorf("cannot migrate config %s before its parent: %w", config, err)
+		}
+		if inherited, err = target.resolvedValues(ctx, chain[1:]); err != nil {
+			return report, err
+		}
+	}
+
+	configKeys := make([]string, 0, len(keyVal))
+	for key := range keyVal {
+		configKeys = append(configKeys, strings.TrimPrefix(key, prefix))
+	}
+	sort.Strings(configKeys)
+	for _, configKey := range configKeys {
+		if renamed, ok := o.renames[configKey]; ok {
+			report.Renamed[configKey] = renamed
+		} else if findField(targetSchema.Fields, configKey) != nil {
+			report.Copied = append(report.Copied, configKey)
+		} else {
+			report.Dropped = append(report.Dropped, configKey)
+		}
+	}
+	// A renamed value wins over a value copied under the same name
+	renamedTo := make(map[string]bool, len(report.Renamed))
+	for old, renamed := range report.Renamed {
+		renamedTo[renamed] = true
+		report.Values[renamed] = keyVal[prefix+old]
+	}
+	copied := report.Copied[:0]
+	for _, configKey := range report.Copied {
+		if renamedTo[configKey] {
+			report.Dropped = append(report.Dropped, configKey)
+			continue
+		}
+		copied = append(copied, configKey)
+		report.Values[configKey] = keyVal[prefix+configKey]
+	}
+	report.Copied = copied
+
+	for i := range targetSchema.Fields {
+		field := &targetSchema.Fields[i]
+		if _, ok := report.Values[field.Name]; ok {
+			continue
+		}
+		// A default would hide the value the config inherits
+		if _, ok := inherited[field.Name]; ok {
+			continue
+		}
+		if def, ok := DefaultValue(field); ok {
+			report.Defaulted = append(report.Defaulted, field.Name)
+			report.Values[field.Name] = def
+		}
+	}
+	sort.Strings(report.Dropped)
+
+	if err := validateValues(targetSchema.Fields, report.Values); err != nil {
+		return report, err
+	}
+	effective := make(map[string]string, len(inherited)+len(report.Values))
+	for _, values := range []map[string]string{inherited, report.Values} {
+		for configKey, value := range values {
+			effective[configKey] = value
+		}
+	}
+	if missing := missingRequiredFields(targetSchema.Fields, effective); len(missing) > 0 {
+		return report, &MissingRequiredFieldsError{Fields: missing}
+	}
+	exists, err = target.ConfigExists(ctx)
+	if err != nil {
+		return report, err
+	}
+	if exists {
+		return report, &ConfigExistsError{Config: config}
+	}
+	if o.dryRun {
+		return report, nil
+	}
+
+	// Expecting every key, and the metadata, to be missing keeps a config created meanwhile from being
+	// mixed with the migrated one
+	revisions := make(map[string]int64, len(report.Values))
+	for configKey := range report.Values {
+		revisions[configKey] = 0
+	}
+	extra := []types.Op{
+		types.PutOp(GetConfCreatedPath(r.App, r.Module, to, config), time.Now().UTC().Format(time.RFC3339)).IfModRevision(0),
+	}
+	if description != "" {
+		extra = append(extra, types.PutOp(GetConfDescriptionPath(r.App, r.Module, to, config), description).IfModRevision(0))
+	}
+	if report.Parent != "" {
+		extra = append(extra, types.PutOp(GetConfParentPath(r.App, r.Module, to, config), report.Parent).IfModRevision(0))
+	}
+	changes, err = target.writeValues(ctx, report.Values, nil, revisions, extra...)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return report, &ConfigExistsError{Config: config}
+	}
+	return report, err
+}
+
+// at returns a client for the named config under the given schema version, sharing r's storage, cache and audit sink.
+func (r *Rigel) at(version int, config string) *Rigel {
+	return &Rigel{
+		Storage:   r.Storage,
+		Cache:     r.Cache,
+		App:       r.App,
+		Module:    r.Module,
+		Version:   version,
+		Config:    config,
+		auditSink: r.auditSink,
+	}
+}
@@ -0,0 +1,154 @@
+package rigel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := New(memstore.New(), "app", "module", 1, "prod")
+	maxTimeout := 60.0
+	v1 := types.Schema{Version: 1, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString},
+		{Name: "timeout_secs", Type: types.TypeInt},
+		{Name: "debug", Type: types.TypeBool},
+	}}
+	v2 := types.Schema{Version: 2, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString, Required: true},
+		{Name: "timeout", Type: types.TypeInt, Constraints: &types.Constraints{Max: &maxTimeout}},
+		{Name: "retries", Type: types.TypeInt, Default: 3},
+	}}
+	for _, schema := range []types.Schema{v1, v2} {
+		if err := rigelClient.AddSchema(ctx, schema); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := rigelClient.SetMany(ctx, map[string]string{"host": "example.com", "timeout_secs": "30", "debug": "true"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	renames := RenameFields(map[string]string{"timeout_secs": "timeout"})
+
+	// A dry run reports the migration without writing anything
+	report, err := rigelClient.MigrateConfig(ctx, 1, 2, "prod", renames, DryRun())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := map[string]string{"host": "example.com", "timeout": "30", "retries": "3"}
+	if !reflect.DeepEqual(report.Values, want) || !reflect.DeepEqual(report.Copied, []string{"host"}) ||
+		!reflect.DeepEqual(report.Defaulted, []string{"retries"}) || !reflect.DeepEqual(report.Dropped, []string{"debug"}) {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+	v2Client := New(rigelClient.Storage, "app", "module", 2, "prod")
+	if exists, _ := v2Client.ConfigExists(ctx); exists {
+		t.Fatalf("Expected a dry run not to create the config")
+	}
+
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "prod", renames); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for key, value := range want {
+		if got, err := v2Client.Get(ctx, key); err != nil || got != value {
+			t.Errorf("Expected %s to be %q, got %q (err %v)", key, value, got, err)
+		}
+	}
+	if got, _ := rigelClient.Get(ctx, "timeout_secs"); got != "30" {
+		t.Errorf("Expected the source config to be left alone, got timeout_secs %q", got)
+	}
+
+	// Migrating again would overwrite the migrated config
+	var existsErr *ConfigExistsError
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "prod", renames); !errors.As(err, &existsErr) {
+		t.Errorf("Expected a ConfigExistsError, got %v", err)
+	}
+
+	// Values are validated against the target schema, and required fields must be filled
+	if err := rigelClient.WithConfig("dev").SetMany(ctx, map[string]string{"timeout_secs": "90"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var validationErrs ValidationErrors
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "dev", renames); !errors.As(err, &validationErrs) {
+		t.Errorf("Expected ValidationErrors, got %v", err)
+	}
+	var missingErr *MissingRequiredFieldsError
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "dev"); !errors.As(err, &missingErr) {
+		t.Errorf("Expected a MissingRequiredFieldsError, got %v", err)
+	}
+}
+
+func TestMigrateConfigMetadata(t *testing.T) {
+	ctx := context.Background()
+	storage := memstore.New()
+	rigelClient := New(storage, "app", "module", 1, "empty")
+	for version := 1; version <= 2; version++ {
+		schema := types.Schema{Version: version, Fields: []types.Field{{Name: "host", Type: types.TypeString}}}
+		if err := rigelClient.AddSchema(ctx, schema); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := rigelClient.CreateConfig(ctx, "empty", "staging", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A config without values is migrated with its description, and marked as created
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "empty"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	migrated := New(storage, "app", "module", 2, "empty")
+	if description, err := migrated.ConfigDescription(ctx); err != nil || description != "staging" {
+		t.Errorf("Expected description %q, got %q (err %v)", "staging", description, err)
+	}
+	if created, err := storage.Get(ctx, GetConfCreatedPath("app", "module", 2, "empty")); err != nil || created == "" {
+		t.Errorf("Expected the migrated config to be marked as created, got %q (err %v)", created, err)
+	}
+}
+
+func TestMigrateConfigWithParent(t *testing.T) {
+	ctx := context.Background()
+	storage := memstore.New()
+	rigelClient := New(storage, "app", "module", 1, "child")
+	for version := 1; version <= 2; version++ {
+		schema := types.Schema{Version: version, Fields: []types.Field{
+			{Name: "host", Type: types.TypeString, Required: true},
+			{Name: "retries", Type: types.TypeInt, Default: 3},
+		}}
+		if err := rigelClient.AddSchema(ctx, schema); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := rigelClient.CreateConfig(ctx, "base", "", map[string]string{"host": "example.com", "retries": "5"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.CreateConfig(ctx, "child", "", nil, WithParent("base")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The parent has to be migrated first
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "child"); err == nil {
+		t.Errorf("Expected migrating a config before its parent to fail")
+	}
+	if _, err := rigelClient.MigrateConfig(ctx, 1, 2, "base"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The migrated config keeps its parent, and keeps inheriting rather than taking defaults
+	report, err := rigelClient.MigrateConfig(ctx, 1, 2, "child")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Parent != "base" || len(report.Defaulted) != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	migrated := New(storage, "app", "module", 2, "child")
+	if parent, err := migrated.ConfigParent(ctx); err != nil || parent != "base" {
+		t.Errorf("Expected parent %q, got %q (err %v)", "base", parent, err)
+	}
+	if retries, err := migrated.Get(ctx, "retries"); err != nil || retries != "5" {
+		t.Errorf("Expected retries to be inherited from base, got %q (err %v)", retries, err)
+	}
+}
@@ -14,9 +14,15 @@ import (
 // Each method of the Storage interface is represented as a function field in this struct.
 // These function fields can be set to specific functions in tests to control the mock's behavior.
 type MockStorage struct {
-	GetFunc   func(ctx context.Context, key string) (string, error)
-	PutFunc   func(ctx context.Context, key string, value string) error
-	WatchFunc func(ctx context.Context, key string, ch chan<- types.Event) error
+	GetFunc                      func(ctx context.Context, key string) (string, error)
+	PutFunc                      func(ctx context.Context, key string, value string) error
+	GetWithPrefixFunc            func(ctx context.Context, prefix string) (map[string]string, error)
+	GetWithRevisionFunc          func(ctx context.Context, key string) (types.KeyValue, error)
+	GetWithPrefixAndRevisionFunc func(ctx context.Context, prefix string) (map[string]types.KeyValue, error)
+	DeleteFunc                   func(ctx context.Context, key string) error
+	DeleteWithPrefixFunc         func(ctx context.Context, prefix string) error
+	TxnFunc                      func(ctx context.Context, ops []types.Op) error
+	WatchFunc                    func(ctx context.Context, key string, ch chan<- types.Event) error
 }
 
 // Get is a method that implements the Get method of the Storage interface.
@@ -33,6 +39,41 @@ func (m *MockStorage) Put(ctx context.Context, key string, value string) error {
 	return m.PutFunc(ctx, key, value)
 }
 
+// GetWithPrefix is a method that implements the GetWithPrefix method of the Storage interface.
+// It calls the function stored in the GetWithPrefixFunc field of the MockStorage struct
+// and returns the result.
+func (m *MockStorage) GetWithPrefix(ctx context.Context, prefix string) (map[string]string, error) {
+	return m.GetWithPrefixFunc(ctx, prefix)
+}
+
+// GetWithRevision is a method that implements the GetWithRevision method of the Storage interface.
+// It calls the function stored in the GetWithRevisionFunc field of the MockStorage struct
+// and returns the result.
+func (m *MockStorage) GetWithRevision(ctx context.Context, key string) (types.KeyValue, error) {
+	return m.GetWithRevisionFunc(ctx, key)
+}
+
+// GetWithPrefixAndRevision is a method that implements the GetWithPrefixAndRevision method of the Storage interface.
+// It calls the function stored in the GetWithPrefixAndRevisionFunc field of the MockStorage struct
+// and returns the result.
+func (m *MockStorage) GetWithPrefixAndRevision(ctx context.Context, prefix string) (map[string]types.KeyValue, error) {
+	return m.GetWithPrefixAndRevisionFunc(ctx, prefix)
+}
+
+// Delete is a method that implements the Delete method of the Storage interface.
+// It calls the function stored in the DeleteFunc field of the MockStorage struct
+// and returns the result.
+func (m *MockStorage) Delete(ctx context.Context, key string) error {
+	return m.DeleteFunc(ctx, key)
+}
+
+// DeleteWithPrefix is a method that implements the DeleteWithPrefix method of the Storage interface.
+// It calls the function stored in the DeleteWithPrefixFunc field of the MockStorage struct
+// and returns the result.
+func (m *MockStorage) DeleteWithPrefix(ctx context.Context, prefix string) error {
+	return m.DeleteWithPrefixFunc(ctx, prefix)
+}
+
 // MockCache is a mock implementation of the Cache interface.
 // It's used for testing purposes to simulate the behavior of a real Cache implementation.
 // Each method of the Cache interface is represented as a function field in this struct.
@@ -82,6 +123,13 @@ func (c *MockCache) Delete(key string) {
 	delete(c.data, key)
 }
 
+// Txn is a method that implements the Txn method of the Storage interface.
+// It calls the function stored in the TxnFunc field of the MockStorage struct
+// and returns the result.
+func (m *MockStorage) Txn(ctx context.Context, ops []types.Op) error {
+	return m.TxnFunc(ctx, ops)
+}
+
 // Watch is a method that implements the Watch method of the Storage interface.
 // It calls the function stored in the WatchFunc field of the MockStorage struct
 // and returns the result.
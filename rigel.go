@@ -3,10 +3,15 @@ package rigel
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/remiges-tech/rigel/etcd"
 	"github.com/remiges-tech/rigel/types"
@@ -18,6 +23,10 @@ const (
 	schemaNameKey        = "name"
 	schemaVersionKey     = "version"
 	schemaFieldsKey      = "fields"
+	schemaConfigKey      = "config"
+	configDescriptionKey = "description"
+	configParentKey      = "parent"
+	configCreatedKey     = "created"
 	defaultEtcdEndpoints = "localhost:2379"
 )
 
@@ -30,11 +39,20 @@ type Rigel struct {
 	Version int
 	Config  string
 	mu      sync.Mutex
+
+	handlersMu        sync.RWMutex
+	changeHandlers    map[string][]ChangeHandler
+	anyChangeHandlers []AnyChangeHandler
+
+	auditSink AuditSink
+
+	flags   sync.Map // flags holds the parsed values of flag fields by storage key; see IsEnabled
+	schemas sync.Map // schemas holds the parsed fields of schemas by storage key; see IsEnabled
 }
 
 // New creates a new instance of Rigel with the provided Storage interface.
 // The Storage interface is used by Rigel to interact with the underlying storage system.
-// Currently, only etcd is supported as a storage system.
+// etcd.EtcdStorage is used in production; memstore.MemStorage can be used in tests and local development.
 func New(storage types.Storage, app string, module string, version int, config string) *Rigel {
 	return &Rigel{
 		Storage: storage,
@@ -125,7 +143,18 @@ func (r *Rigel) KeyExistsInSchema(ctx context.Context, key string) (bool, error)
 }
 
 // Set sets a value of a config key in the storage.
-func (r *Rigel) Set(ctx context.Context, configKey string, value string) error {
+// If the named config does not exist yet, Set creates it. That fails with a *MissingRequiredFieldsError
+// if the schema has other required fields without a default; use CreateConfig to set them all at once.
+// Pass ExpectRevision to only store the value if it has not changed since it was read with GetWithRevision;
+// otherwise Set fails with a *ConflictError.
+// An empty value leaves the key without a value of its own, like DeleteKey: Get then returns the value
+// inherited from a parent config, or the default.
+func (r *Rigel) Set(ctx context.Context, configKey string, value string, opts ...SetOption) (err error) {
+	var changes []HistoryEntry
+	defer func() {
+		r.auditWrite(ctx, AuditSet, map[string]string{configKey: value}, nil, changes, err)
+	}()
+
 	// Check if the key exists in the schema
 	exists, err := r.KeyExistsInSchema(ctx, configKey)
 	if err != nil {
@@ -142,34 +171,388 @@ func (r *Rigel) Set(ctx context.Context, configKey string, value string) error {
 	}
 
 	// Find the field in the schema
-	var field *types.Field
-	for _, f := range schemaFields {
-		if f.Name == configKey {
-			field = &f
-			break
+	field := findField(schemaFields, configKey)
+
+	// Validate the value against the field's constraints
+	if err := ValidateValue(value, field); err != nil {
+		return err
+	}
+
+	// A new named config must not be created with required fields missing
+	if missing := missingRequiredFields(schemaFields, map[string]string{configKey: value}); len(missing) > 0 {
+		configExists, err := r.ConfigExists(ctx)
+		if err != nil {
+			return err
+		}
+		if !configExists {
+			return &MissingRequiredFieldsError{Fields: missing}
 		}
 	}
 
-	// Validate the value against the field's constraints
-	if !ValidateValueAgainstConstraints(value, field) {
-		return fmt.Errorf("value does not meet the constraints of the field")
+	// Set the value in the storage, as a compare-and-swap if an expected revision was given
+	var revisions map[string]int64
+	if rev, ok := newSetOptions(opts).expectedRevision(configKey); ok {
+		revisions = map[string]int64{configKey: rev}
 	}
+	changes, err = r.writeValues(ctx, map[string]string{configKey: value}, nil, revisions)
+	return err
+}
 
-	// Construct the key for the parameter
-	key := GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey)
+// SetOption configures a write made by Set or SetMany.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	revision  *int64
+	revisions map[string]int64
+}
+
+// ExpectRevision makes Set fail with a *ConflictError, without storing anything, unless the mod revision of
+// the value is still rev. Use the revision returned by GetWithRevision, or 0 if the key must have no value yet.
+func ExpectRevision(rev int64) SetOption {
+	return func(o *setOptions) {
+		o.revision = &rev
+	}
+}
+
+// ExpectRevisions is like ExpectRevision for several keys, keyed by config key. Keys that are not in revs
+// are written unconditionally.
+func ExpectRevisions(revs map[string]int64) SetOption {
+	return func(o *setOptions) {
+		o.revisions = revs
+	}
+}
+
+func newSetOptions(opts []SetOption) *setOptions {
+	o := &setOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// expectedRevision returns the revision configKey is expected to have, if any.
+func (o *setOptions) expectedRevision(configKey string) (int64, bool) {
+	if o.revision != nil {
+		return *o.revision, true
+	}
+	rev, ok := o.revisions[configKey]
+	return rev, ok
+}
+
+// setError converts a failed write into the error returned to the caller. A conflict on a config key is
+// reported as a *ConflictError naming the config key, and one on the metadata of the named config as a
+// *ConflictError naming its path under the config, such as "meta/created".
+func (r *Rigel) setError(err error) error {
+	var conflict *types.ConflictError
+	if errors.As(err, &conflict) {
+		key := r.configKeyOf(conflict.Key)
+		if key == conflict.Key {
+			key = strings.TrimPrefix(key, GetConfPath(r.App, r.Module, r.Version, r.Config)+"/")
+		}
+		return &ConflictError{
+			Key:              key,
+			ExpectedRevision: conflict.ExpectedRevision,
+			ModRevision:      conflict.ModRevision,
+		}
+	}
+	return fmt.Errorf("failed to set config value: %w", err)
+}
+
+// ValidateValues checks values, keyed by config key, against the schema without storing them.
+// It returns a *KeyNotFoundError if a key is not in the schema, and a ValidationErrors listing
+// every invalid value otherwise.
+func (r *Rigel) ValidateValues(ctx context.Context, values map[string]string) error {
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+	return validateValues(schemaFields, values)
+}
+
+// CreateConfig creates the named config name, under the client's schema version, with the given
+// description and values, keyed by config key. Every value is validated against the schema before
+// anything is written; invalid values are reported together in a ValidationErrors. The values, the
+// description and the creation time are stored in a single transaction. CreateConfig fails with a *MissingRequiredFieldsError
+// if a required field has neither a value nor a default, and with a *ConfigExistsError if the named
+// config already exists. Pass WithParent to have the config inherit the values it does not set.
+func (r *Rigel) CreateConfig(ctx context.Context, name, description string, values map[string]string, opts ...CreateOption) (err error) {
+	target := r.at(r.Version, name)
+	var changes []HistoryEntry
+	defer func() {
+		target.auditWrite(ctx, AuditCreateConfig, values, nil, changes, err)
+	}()
+
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	if err := validateValues(schemaFields, values); err != nil {
+		return err
+	}
+
+	var o createOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// Values the parent has count towards the required fields
+	effective := values
+	if o.parent != "" {
+		chain, err := target.checkParent(ctx, o.parent)
+		if err != nil {
+			return err
+		}
+		if effective, err = target.resolvedValues(ctx, chain[1:]); err != nil {
+			return err
+		}
+		for configKey, value := range values {
+			effective[configKey] = value
+		}
+	}
+	if missing := missingRequiredFields(schemaFields, effective); len(missing) > 0 {
+		return &MissingRequiredFieldsError{Fields: missing}
+	}
+
+	exists, err := target.ConfigExists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &ConfigExistsError{Config: name}
+	}
+
+	// Every key, and the metadata, must still be missing when the config is written,
+	// so that two callers creating the same config cannot both succeed. The creation time is
+	// always written, so that a config created without values or a description exists too.
+	revisions := make(map[string]int64, len(values))
+	for configKey := range values {
+		revisions[configKey] = 0
+	}
+	extra := []types.Op{
+		types.PutOp(GetConfCreatedPath(r.App, r.Module, r.Version, name), time.Now().UTC().Format(time.RFC3339)).IfModRevision(0),
+	}
+	if description != "" {
+		extra = append(extra, types.PutOp(GetConfDescriptionPath(r.App, r.Module, r.Version, name), description).IfModRevision(0))
+	}
+	if o.parent != "" {
+		extra = append(extra, types.PutOp(GetConfParentPath(r.App, r.Module, r.Version, name), o.parent).IfModRevision(0))
+	}
+	changes, err = target.writeValues(ctx, values, nil, revisions, extra...)
+	// Nothing was expected to be there yet, so a conflict means another caller created the config first
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return &ConfigExistsError{Config: name}
+	}
+	return err
+}
+
+// ConfigDescription returns the description the named config was created with, or "" if it has none.
+func (r *Rigel) ConfigDescription(ctx context.Context) (string, error) {
+	kv, err := r.Storage.GetWithRevision(ctx, GetConfDescriptionPath(r.App, r.Module, r.Version, r.Config))
+	if err != nil {
+		return "", fmt.Errorf("failed to get config description: %w", err)
+	}
+	return kv.Value, nil
+}
+
+// SetMany sets the values of several config keys, keyed by config key, in a single transaction,
+// so that either all of them are stored or none is. Every value is validated against the schema
+// first; invalid values are reported together in a ValidationErrors. A transaction holds the changes
+// of at most 64 keys; SetMany refuses more with a *TooManyChangesError.
+// Like Set, SetMany creates the named config if it does not exist yet, and fails with a
+// *MissingRequiredFieldsError if that would leave required fields without a value or default.
+// Pass ExpectRevisions to only store the values if none of them has changed since it was read;
+// otherwise nothing is stored and SetMany fails with a *ConflictError.
+func (r *Rigel) SetMany(ctx context.Context, values map[string]string, opts ...SetOption) (err error) {
+	var changes []HistoryEntry
+	defer func() {
+		r.auditWrite(ctx, AuditSetMany, values, nil, changes, err)
+	}()
+
+	o := newSetOptions(opts)
+	if o.revision != nil {
+		return fmt.Errorf("ExpectRevision only applies to Set, use ExpectRevisions with SetMany")
+	}
+
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	if err := validateValues(schemaFields, values); err != nil {
+		return err
+	}
+
+	// A new named config must not be created with required fields missing
+	if missing := missingRequiredFields(schemaFields, values); len(missing) > 0 {
+		configExists, err := r.ConfigExists(ctx)
+		if err != nil {
+			return err
+		}
+		if !configExists {
+			return &MissingRequiredFieldsError{Fields: missing}
+		}
+	}
+
+	changes, err = r.putValues(ctx, values, o.revisions)
+	return err
+}
+
+// putValues stores values, keyed by config key, in the named config in a single transaction and updates the cache.
+// Keys that have an entry in revisions are only stored if their mod revision still matches it.
+// It returns the changes recorded in the history.
+func (r *Rigel) putValues(ctx context.Context, values map[string]string, revisions map[string]int64) ([]HistoryEntry, error) {
+	return r.writeValues(ctx, values, nil, revisions)
+}
+
+// ConfigExists reports whether the named config has any values or metadata stored under it.
+func (r *Rigel) ConfigExists(ctx context.Context) (bool, error) {
+	prefix := GetConfPath(r.App, r.Module, r.Version, r.Config) + "/"
+	keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return false, fmt.Errorf("failed to get config: %w", err)
+	}
+	for key := range keyVal {
+		if IsConfigDataKey(strings.TrimPrefix(key, prefix)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SchemaExists reports whether the schema version has been added.
+func (r *Rigel) SchemaExists(ctx context.Context) (bool, error) {
+	keyVal, err := r.Storage.GetWithPrefix(ctx, GetSchemaFieldsPath(r.App, r.Module, r.Version))
+	if err != nil {
+		return false, fmt.Errorf("failed to get schema: %w", err)
+	}
+	return len(keyVal) > 0, nil
+}
+
+// DeleteKey removes the value of a config key from the named config.
+// The key must exist in the schema. Deleting a key that has no value is not an error.
+func (r *Rigel) DeleteKey(ctx context.Context, configKey string) (err error) {
+	var changes []HistoryEntry
+	defer func() {
+		r.auditWrite(ctx, AuditDeleteKey, nil, []string{configKey}, changes, err)
+	}()
+
+	exists, err := r.KeyExistsInSchema(ctx, configKey)
+	if err != nil {
+		return fmt.Errorf("failed to check if key exists in schema: %w", err)
+	}
+	if !exists {
+		return &KeyNotFoundError{Key: configKey}
+	}
+
+	changes, err = r.writeValues(ctx, nil, []string{configKey}, nil)
+	return err
+}
+
+// DeleteConfig removes the named config, including all of its values.
+// It refuses with a *ConfigInUseError if other named configs have the config as their parent.
+func (r *Rigel) DeleteConfig(ctx context.Context) (err error) {
+	record := r.newAuditRecord(ctx, AuditDeleteConfig)
+	defer func() {
+		r.audit(ctx, []AuditRecord{record}, err)
+	}()
+
+	children, err := r.childConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return &ConfigInUseError{Config: r.Config, Children: children}
+	}
+
+	// The trailing slash keeps e.g. "prod" from matching "prod-eu"
+	prefix := GetConfPath(r.App, r.Module, r.Version, r.Config) + "/"
+
+	existing, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	err = r.Storage.DeleteWithPrefix(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to delete config: %w", err)
+	}
+
+	for key := range existing {
+		r.Cache.Delete(key)
+	}
+
+	return nil
+}
+
+// DeleteSchema removes a schema version, including its field descriptions.
+// If named configs still exist under the schema version, DeleteSchema refuses with a
+// *SchemaInUseError unless force is true, in which case the named configs are deleted too.
+func (r *Rigel) DeleteSchema(ctx context.Context, force bool) (err error) {
+	record := r.newAuditRecord(ctx, AuditDeleteSchema)
+	record.Config = ""
+	defer func() {
+		r.audit(ctx, []AuditRecord{record}, err)
+	}()
+
+	configs, err := r.ListConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(configs) > 0 && !force {
+		return &SchemaInUseError{App: r.App, Module: r.Module, Version: r.Version, Configs: configs}
+	}
+
+	prefix := GetSchemaPath(r.App, r.Module, r.Version)
+
+	existing, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+	record.OldValue = existing[GetSchemaFieldsPath(r.App, r.Module, r.Version)]
 
-	// Set the value in the storage
-	err = r.Storage.Put(ctx, key, value)
+	err = r.Storage.DeleteWithPrefix(ctx, prefix)
 	if err != nil {
-		return fmt.Errorf("failed to set config value: %w", err)
+		return fmt.Errorf("failed to delete schema: %w", err)
 	}
 
-	// Update the value in the cache
-	r.Cache.Set(key, value)
+	for key := range existing {
+		r.Cache.Delete(key)
+	}
+	r.schemas.Delete(GetSchemaFieldsPath(r.App, r.Module, r.Version))
 
 	return nil
 }
 
+// ListConfigs returns the names of the named configs stored under the schema version, sorted by name.
+func (r *Rigel) ListConfigs(ctx context.Context) ([]string, error) {
+	return r.listConfigs(ctx, r.Version)
+}
+
+// listConfigs returns the names of the named configs stored under the given version of the schema, sorted by name.
+// A config whose values are all deleted, but whose history is still kept, is not listed.
+func (r *Rigel) listConfigs(ctx context.Context, version int) ([]string, error) {
+	prefix := GetSchemaPath(r.App, r.Module, version) + schemaConfigKey + "/"
+
+	keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configs: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var configs []string
+	for key := range keyVal {
+		name, sub, _ := strings.Cut(strings.TrimPrefix(key, prefix), "/")
+		if name != "" && IsConfigDataKey(sub) && !seen[name] {
+			seen[name] = true
+			configs = append(configs, name)
+		}
+	}
+	sort.Strings(configs)
+
+	return configs, nil
+}
+
 // LoadConfig retrieves the configuration data associated with the provided configName.
 // It then unmarshals this data into the provided configStruct.
 //
@@ -190,6 +573,12 @@ func (r *Rigel) LoadConfig(ctx context.Context, configStruct any) error {
 		return err
 	}
 
+	return unmarshalConfig(configMap, configStruct)
+}
+
+// unmarshalConfig fills configStruct from the configuration map by going through JSON,
+// so the struct's json tags decide which field receives which value.
+func unmarshalConfig(configMap map[string]any, configStruct any) error {
 	// Marshal the configuration map into a JSON string
 	configJSON, err := json.Marshal(configMap)
 	if err != nil {
@@ -205,10 +594,33 @@ func (r *Rigel) LoadConfig(ctx context.Context, configStruct any) error {
 	return nil
 }
 
-// AddSchema adds a new schema to the Rigel storage.
-// If a schema with the same name and version already exists in the storage,
-// AddSchema will override the existing schema with the new one.
-func (r *Rigel) AddSchema(ctx context.Context, schema types.Schema) error {
+// AddSchema adds a new schema version to the Rigel storage.
+// Published schema versions are immutable: adding a schema that is identical to the stored version
+// does nothing, and adding one that differs fails with a *SchemaChangedError describing the differences.
+// Pass ForceOverwrite to replace the stored version anyway; that fails with a *SchemaIncompatibleError
+// if a named config under the version would no longer be valid with the new schema. The configs are
+// checked before the schema is written, not in the same transaction, so a value set in between is not
+// checked; overwrite a schema while its configs are not being changed.
+// The fields, their descriptions and the schema description are written in a single transaction, which
+// holds the descriptions of at most 126 fields (see types.MaxTxnOps).
+func (r *Rigel) AddSchema(ctx context.Context, schema types.Schema, opts ...AddSchemaOption) (err error) {
+	record := r.newAuditRecord(ctx, AuditAddSchema)
+	record.Version = schema.Version
+	record.Config = ""
+	defer func() {
+		r.audit(ctx, []AuditRecord{record}, err)
+	}()
+
+	o := &addSchemaOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Make sure defaults are valid before anything is stored
+	if err := ValidateFields(schema.Fields); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
 	// Convert fields to JSON
 	fieldsJson, err := json.Marshal(schema.Fields)
 	if err != nil {
@@ -217,33 +629,138 @@ func (r *Rigel) AddSchema(ctx context.Context, schema types.Schema) error {
 
 	// Get the base schema path using the version from the schema
 	baseSchemaPath := GetSchemaPath(r.App, r.Module, schema.Version)
-
-	// Store fields
 	fieldsKey := baseSchemaPath + schemaFieldsKey
-	err = r.Storage.Put(ctx, fieldsKey, string(fieldsJson))
+	descriptionKey := baseSchemaPath + schemaDescriptionKey
+
+	// Compare with the stored version, if any
+	current, err := r.Storage.GetWithRevision(ctx, fieldsKey)
 	if err != nil {
-		return fmt.Errorf("failed to store fields: %v", err)
+		return fmt.Errorf("failed to get schema: %w", err)
 	}
+	record.OldValue = current.Value
+	record.NewValue = string(fieldsJson)
+
+	var removed []types.Field
+	if current.ModRevision != 0 {
+		existing := types.Schema{Version: schema.Version}
+		if err := json.Unmarshal([]byte(current.Value), &existing.Fields); err != nil {
+			return fmt.Errorf("failed to unmarshal fields: %w", err)
+		}
+		if existing.Description, err = r.Storage.Get(ctx, descriptionKey); err != nil {
+			return fmt.Errorf("failed to get schema description: %w", err)
+		}
 
-	// Store description
-	descriptionKey := baseSchemaPath + schemaDescriptionKey
-	err = r.Storage.Put(ctx, descriptionKey, schema.Description)
-	if err != nil {
-		return fmt.Errorf("failed to store description: %v", err)
+		diff := DiffSchemas(existing, schema)
+		if diff.Empty() {
+			return nil
+		}
+		if !o.force {
+			return &SchemaChangedError{App: r.App, Module: r.Module, Version: schema.Version, Diff: diff}
+		}
+		if err := r.checkConfigsAgainst(ctx, schema); err != nil {
+			return err
+		}
+		removed = diff.Removed
 	}
 
+	// Store fields and descriptions, as long as nobody changed the fields since they were compared
+	ops := []types.Op{
+		types.PutOp(fieldsKey, string(fieldsJson)).IfModRevision(current.ModRevision),
+		types.PutOp(descriptionKey, schema.Description),
+	}
 	for _, field := range schema.Fields {
-		//store felid description
-		felidDescriptionKey := baseSchemaPath + schemaFieldsKey + "/" + field.Name
-		err = r.Storage.Put(ctx, felidDescriptionKey, field.Description)
-		if err != nil {
-			return fmt.Errorf("failed to store description: %v", err)
+		ops = append(ops, types.PutOp(fieldsKey+"/"+field.Name, field.Description))
+	}
+	for _, field := range removed {
+		ops = append(ops, types.DeleteOp(fieldsKey+"/"+field.Name))
+	}
+	err = r.Storage.Txn(ctx, ops)
+	if err != nil {
+		var conflict *types.ConflictError
+		if errors.As(err, &conflict) {
+			return fmt.Errorf("schema %s/%s version %d was changed while it was being added: %w", r.App, r.Module, schema.Version, err)
 		}
+		return fmt.Errorf("failed to store schema: %v", err)
 	}
+	// IsEnabled keeps the fields it has read
+	r.schemas.Delete(fieldsKey)
 
 	return nil
 }
 
+// AddSchemaOption configures AddSchema.
+type AddSchemaOption func(*addSchemaOptions)
+
+type addSchemaOptions struct {
+	force bool
+}
+
+// ForceOverwrite lets AddSchema replace a stored schema version that differs from the new schema,
+// as long as every named config under the version stays valid.
+func ForceOverwrite() AddSchemaOption {
+	return func(o *addSchemaOptions) {
+		o.force = true
+	}
+}
+
+// checkConfigsAgainst returns a *SchemaIncompatibleError if a named config under the version of
+// schema has values that are not valid with it, or lacks values it requires.
+func (r *Rigel) checkConfigsAgainst(ctx context.Context, schema types.Schema) error {
+	problems, err := r.configProblems(ctx, schema.Version, schema)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return &SchemaIncompatibleError{App: r.App, Module: r.Module, Version: schema.Version, Configs: problems}
+	}
+	return nil
+}
+
+// configProblems validates every named config under version against schema, which need not be the
+// schema of that version. It returns the problems of each config that is not valid with it.
+func (r *Rigel) configProblems(ctx context.Context, version int, schema types.Schema) (map[string][]string, error) {
+	configs, err := r.listConfigs(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make(map[string]map[string]string, len(configs))
+	parents := make(map[string]string, len(configs))
+	for _, config := range configs {
+		prefix := GetConfKeyPath(r.App, r.Module, version, config, "")
+		keyVal, err := r.Storage.GetWithPrefix(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config %s: %w", config, err)
+		}
+		values := make(map[string]string, len(keyVal))
+		for key, value := range keyVal {
+			values[strings.TrimPrefix(key, prefix)] = value
+		}
+		layers[config] = values
+		if parents[config], err = r.Storage.Get(ctx, GetConfParentPath(r.App, r.Module, version, config)); err != nil {
+			return nil, fmt.Errorf("failed to get parent of config %s: %w", config, err)
+		}
+	}
+
+	problems := make(map[string][]string)
+	for _, config := range configs {
+		values := layers[config]
+		for _, configKey := range sortedKeys(values) {
+			field := findField(schema.Fields, configKey)
+			if field == nil {
+				problems[config] = append(problems[config], fmt.Sprintf("%s has a value but is not in the schema", configKey))
+			} else if err := ValidateValue(values[configKey], field); err != nil {
+				problems[config] = append(problems[config], err.Error())
+			}
+		}
+		// Required fields may have their values in a parent
+		if missing := missingRequiredFields(schema.Fields, mergeParents(config, layers, parents)); len(missing) > 0 {
+			problems[config] = append(problems[config], fmt.Sprintf("missing required fields: %s", strings.Join(missing, ", ")))
+		}
+	}
+	return problems, nil
+}
+
 // getSchemaFields retrieves the schema fields
 func (r *Rigel) getSchemaFields(ctx context.Context) ([]types.Field, error) {
 	schemaFieldsKey := GetSchemaFieldsPath(r.App, r.Module, r.Version)
@@ -264,19 +781,28 @@ func (r *Rigel) getSchemaFields(ctx context.Context) ([]types.Field, error) {
 
 // GetSchema retrieves a schema (fields and metadata)
 func (r *Rigel) GetSchema(ctx context.Context) (*types.Schema, error) {
-	schemaDescriptionKey := GetSchemaDescriptionPath(r.App, r.Module, r.Version)
+	return r.getSchemaVersion(ctx, r.Version)
+}
+
+// getSchemaVersion retrieves the given version of the client's schema.
+func (r *Rigel) getSchemaVersion(ctx context.Context, version int) (*types.Schema, error) {
+	schemaDescriptionKey := GetSchemaDescriptionPath(r.App, r.Module, version)
 	description, err := r.Storage.Get(ctx, schemaDescriptionKey)
 	if err != nil {
 		return nil, err
 	}
 
-	fields, err := r.getSchemaFields(ctx)
+	fieldsStr, err := r.Storage.Get(ctx, GetSchemaFieldsPath(r.App, r.Module, version))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get schema fields: %w", err)
 	}
+	var fields []types.Field
+	if err := json.Unmarshal([]byte(fieldsStr), &fields); err != nil {
+		return nil, fmt.Errorf("failed to get schema fields: failed to unmarshal fields: %w", err)
+	}
 
 	schema := &types.Schema{
-		Version:     r.Version,
+		Version:     version,
 		Fields:      fields,
 		Description: description,
 	}
@@ -305,14 +831,70 @@ func (r *Rigel) constructConfigMap(ctx context.Context) (map[string]any, error)
 	if err != nil {
 		return nil, err
 	}
-	// Construct the configuration map
-	config := make(map[string]any)
+	// Retrieve the configuration values for the fields
+	values, err := r.getConfigValues(ctx, schemaFields)
+	if err != nil {
+		return nil, err
+	}
+	return buildConfigMap(schemaFields, values)
+}
+
+// getConfigValues retrieves the configuration value of every field, keyed by field name.
+// Fields the named config has no value for take the value of the nearest parent that has one.
+func (r *Rigel) getConfigValues(ctx context.Context, schemaFields []types.Field) (map[string]string, error) {
+	values := make(map[string]string, len(schemaFields))
+	var chain []string
 	for _, field := range schemaFields {
-		// Retrieve the configuration value for the field
 		valueStr, err := r.getConfigValue(ctx, field.Name)
 		if err != nil {
 			return nil, err
 		}
+		if valueStr == "" {
+			// The parents are only looked up once a value is found missing
+			if chain == nil {
+				if chain, err = r.parentChain(ctx, r.Config); err != nil {
+					return nil, err
+				}
+			}
+			if valueStr, _, err = r.inheritedValue(ctx, chain[1:], field.Name); err != nil {
+				return nil, err
+			}
+		}
+		values[field.Name] = valueStr
+	}
+	return values, nil
+}
+
+// getInheritedValue returns the value of configKey in the nearest parent of the named config that has one, or "".
+func (r *Rigel) getInheritedValue(ctx context.Context, configKey string) (string, error) {
+	chain, err := r.parentChain(ctx, r.Config)
+	if err != nil {
+		return "", err
+	}
+	value, _, err := r.inheritedValue(ctx, chain[1:], configKey)
+	return value, err
+}
+
+// buildConfigMap converts the values of the fields to their types and returns them keyed by field name.
+// A field without a value gets its default. A field with neither is left out of the map, so the
+// matching struct field keeps its value, unless the field is required.
+func buildConfigMap(schemaFields []types.Field, values map[string]string) (map[string]any, error) {
+	if missing := missingRequiredFields(schemaFields, values); len(missing) > 0 {
+		return nil, &MissingRequiredFieldsError{Fields: missing}
+	}
+
+	// Construct the configuration map
+	config := make(map[string]any)
+	for i := range schemaFields {
+		field := &schemaFields[i]
+		valueStr := values[field.Name]
+		if valueStr == "" {
+			def, ok := DefaultValue(field)
+			if !ok {
+				continue
+			}
+			valueStr = def
+		}
 
 		// Convert the value to the correct type based on the field type
 		value, err := convertToType(valueStr, field.Type)
@@ -334,17 +916,156 @@ func (e *KeyNotFoundError) Error() string {
 	return fmt.Sprintf("key %s not found in config", e.Key)
 }
 
-// Get retrieves a value from the storage based on the provided key.
-// It converts the retrieved value to the correct type based on the field type.
-// If the field type is not "int" or "bool", the value is assumed to be a string.
-// get retrieves a value from the cache or storage and returns it as a string.
+// MissingRequiredFieldsError is returned when required fields have neither a value nor a default.
+type MissingRequiredFieldsError struct {
+	Fields []string
+}
+
+func (e *MissingRequiredFieldsError) Error() string {
+	return fmt.Sprintf("missing required fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// ConfigExistsError is returned when creating a named config that already exists.
+type ConfigExistsError struct {
+	Config string
+}
+
+func (e *ConfigExistsError) Error() string {
+	return fmt.Sprintf("config %s already exists", e.Config)
+}
+
+// SchemaInUseError is returned when deleting a schema version that still has named configs.
+type SchemaInUseError struct {
+	App     string
+	Module  string
+	Version int
+	Configs []string
+}
+
+func (e *SchemaInUseError) Error() string {
+	return fmt.Sprintf("schema %s/%s version %d is used by configs: %s", e.App, e.Module, e.Version, strings.Join(e.Configs, ", "))
+}
+
+// SchemaChangedError is returned by AddSchema when the schema version already exists and differs from
+// the new schema. Published versions are immutable; add the new schema as a new version instead.
+type SchemaChangedError struct {
+	App     string
+	Module  string
+	Version int
+	Diff    SchemaDiff // Diff describes how the new schema differs from the stored one
+}
+
+func (e *SchemaChangedError) Error() string {
+	return fmt.Sprintf("schema %s/%s version %d already exists with differences:\n%s", e.App, e.Module, e.Version, e.Diff)
+}
+
+// SchemaIncompatibleError is returned by AddSchema with ForceOverwrite when named configs under the
+// schema version would not be valid with the new schema.
+type SchemaIncompatibleError struct {
+	App     string
+	Module  string
+	Version int
+	Configs map[string][]string // Configs lists the problems of each named config that would become invalid
+}
+
+func (e *SchemaIncompatibleError) Error() string {
+	configs := make([]string, 0, len(e.Configs))
+	for config := range e.Configs {
+		configs = append(configs, config)
+	}
+	sort.Strings(configs)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema %s/%s version %d would make configs invalid:", e.App, e.Module, e.Version)
+	for _, config := range configs {
+		fmt.Fprintf(&b, "\n%s: %s", config, strings.Join(e.Configs[config], "; "))
+	}
+	return b.String()
+}
+
+// Names of the checks reported in ValidationError.Constraint. Apart from ConstraintType, they match
+// the names of the constraints in a schema.
+const (
+	ConstraintType         = "type"
+	ConstraintMin          = "min"
+	ConstraintMax          = "max"
+	ConstraintExclusiveMin = "exclusiveMin"
+	ConstraintExclusiveMax = "exclusiveMax"
+	ConstraintMinLength    = "minLength"
+	ConstraintMaxLength    = "maxLength"
+	ConstraintPattern      = "pattern"
+	ConstraintMultipleOf   = "multipleOf"
+	ConstraintEnum         = "enum"
+	ConstraintRules        = "rules" // ConstraintRules is reported for a flag value whose targeting rules are not valid
+)
+
+// TooManyChangesError is returned when a single write changes more keys than fit in one storage
+// transaction. Every changed key takes two operations, one for its value and one for its history.
+type TooManyChangesError struct {
+	Keys int // Keys is the number of keys the write changes
+	Max  int // Max is the most keys a single write can change
+}
+
+func (e *TooManyChangesError) Error() string {
+	return fmt.Sprintf("cannot change %d keys at once, at most %d keys can be changed in one write", e.Keys, e.Max)
+}
+
+// ConflictError is returned when a write made with ExpectRevision or ExpectRevisions finds that the
+// value has been changed since it was read.
+type ConflictError struct {
+	Key              string // Key is the config key, or the path of the metadata under the config
+	ExpectedRevision int64
+	ModRevision      int64 // ModRevision is the current mod revision of the value, 0 if it has none
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("config key %s was modified since it was read: expected revision %d, found %d", e.Key, e.ExpectedRevision, e.ModRevision)
+}
+
+// ValidationError is returned when a value does not meet the type or a constraint of its field.
+type ValidationError struct {
+	Field      string // Field is the name of the schema field
+	Constraint string // Constraint is the check that failed, one of the Constraint constants
+	Value      string // Value is the rejected value; it is left empty for secret fields
+	Limit      string // Limit is what the constraint requires: the type, bound, pattern or enum values, or what is wrong with flag rules
+}
+
+func (e *ValidationError) Error() string {
+	value := fmt.Sprintf("%q", e.Value)
+	if e.Value == "" {
+		value = "value"
+	} else {
+		value = "value " + value
+	}
+	if e.Constraint == ConstraintType {
+		return fmt.Sprintf("%s of field %s is not a valid %s", value, e.Field, e.Limit)
+	}
+	return fmt.Sprintf("%s of field %s does not meet constraint %s (%s)", value, e.Field, e.Constraint, e.Limit)
+}
+
+// ValidationErrors is returned when several values fail validation. It holds one *ValidationError per value.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Get returns the value of configKey in the named config as a string. The value is taken from the
+// cache if it is there, else from the storage: the named config's own value if it has a non-empty
+// one, else the value of the nearest parent config that has one, else the default of the field.
+// A key with none of these has the value "". The value found is cached.
 func (r *Rigel) Get(ctx context.Context, configKey string) (string, error) {
 	// Check if the key exists in the schema
-	exists, err := r.KeyExistsInSchema(ctx, configKey)
+	schemaFields, err := r.getSchemaFields(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to check if key exists in schema: %w", err)
 	}
-	if !exists {
+	field := findField(schemaFields, configKey)
+	if field == nil {
 		return "", &KeyNotFoundError{Key: configKey}
 	}
 
@@ -363,12 +1084,61 @@ func (r *Rigel) Get(ctx context.Context, configKey string) (string, error) {
 		return "", &KeyNotFoundError{Key: key}
 	}
 
+	// A key the config does not set is inherited from its parents
+	if valueStr == "" {
+		if valueStr, err = r.getInheritedValue(ctx, configKey); err != nil {
+			return "", err
+		}
+	}
+
+	// Fall back to the schema default for a key that was never set
+	if valueStr == "" {
+		if def, ok := DefaultValue(field); ok {
+			valueStr = def
+		}
+	}
+
 	// Store the value in the cache
 	r.Cache.Set(key, valueStr)
 
 	return valueStr, nil
 }
 
+// GetWithRevision is like Get, but also returns the mod revision of the stored value, the revision at which
+// it was last changed, or 0 if the key has no value in the named config itself. Pass the revision to Set with
+// ExpectRevision to make sure the value has not changed since it was read. GetWithRevision always reads
+// from the storage, not from the cache.
+func (r *Rigel) GetWithRevision(ctx context.Context, configKey string) (string, int64, error) {
+	schemaFields, err := r.getSchemaFields(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to check if key exists in schema: %w", err)
+	}
+	field := findField(schemaFields, configKey)
+	if field == nil {
+		return "", 0, &KeyNotFoundError{Key: configKey}
+	}
+
+	kv, err := r.Storage.GetWithRevision(ctx, GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get config value: %w", err)
+	}
+
+	value := kv.Value
+	if value == "" {
+		if value, err = r.getInheritedValue(ctx, configKey); err != nil {
+			return "", 0, err
+		}
+	}
+
+	// Fall back to the schema default for a key that was never set
+	if value == "" {
+		if def, ok := DefaultValue(field); ok {
+			value = def
+		}
+	}
+	return value, kv.ModRevision, nil
+}
+
 func (r *Rigel) GetInt(ctx context.Context, configKey string) (int, error) {
 	valueStr, err := r.Get(ctx, configKey)
 	if err != nil {
@@ -413,50 +1183,235 @@ func (r *Rigel) GetString(ctx context.Context, configKey string) (string, error)
 	return valueStr, nil
 }
 
+// GetDuration retrieves the value of a duration field, such as "1m30s".
+func (r *Rigel) GetDuration(ctx context.Context, configKey string) (time.Duration, error) {
+	valueStr, err := r.Get(ctx, configKey)
+	if err != nil {
+		return 0, err
+	}
+	durationValue, err := time.ParseDuration(valueStr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert value to duration: %w", err)
+	}
+	return durationValue, nil
+}
+
+// GetStringSlice retrieves the value of a []string field.
+func (r *Rigel) GetStringSlice(ctx context.Context, configKey string) ([]string, error) {
+	valueStr, err := r.Get(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	var sliceValue []string
+	if err := json.Unmarshal([]byte(valueStr), &sliceValue); err != nil {
+		return nil, fmt.Errorf("failed to convert value to []string: %w", err)
+	}
+	return sliceValue, nil
+}
+
+// GetIntSlice retrieves the value of an []int field.
+func (r *Rigel) GetIntSlice(ctx context.Context, configKey string) ([]int, error) {
+	valueStr, err := r.Get(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	var sliceValue []int
+	if err := json.Unmarshal([]byte(valueStr), &sliceValue); err != nil {
+		return nil, fmt.Errorf("failed to convert value to []int: %w", err)
+	}
+	return sliceValue, nil
+}
+
+// GetStringMap retrieves the value of a map[string]string field.
+func (r *Rigel) GetStringMap(ctx context.Context, configKey string) (map[string]string, error) {
+	valueStr, err := r.Get(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	var mapValue map[string]string
+	if err := json.Unmarshal([]byte(valueStr), &mapValue); err != nil {
+		return nil, fmt.Errorf("failed to convert value to map[string]string: %w", err)
+	}
+	return mapValue, nil
+}
+
+// GetJSON retrieves the value of a json field and unmarshals it into v.
+func (r *Rigel) GetJSON(ctx context.Context, configKey string, v any) error {
+	valueStr, err := r.Get(ctx, configKey)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(valueStr), v); err != nil {
+		return fmt.Errorf("failed to unmarshal json value: %w", err)
+	}
+	return nil
+}
+
+// GetURL retrieves the value of a url field.
+func (r *Rigel) GetURL(ctx context.Context, configKey string) (*url.URL, error) {
+	valueStr, err := r.Get(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	urlValue, err := parseURL(valueStr)
+	if err != nil {
+		return nil, err
+	}
+	return urlValue, nil
+}
+
 // convertToType converts a string value to the specified type.
 func convertToType(valueStr string, fieldType string) (interface{}, error) {
 	switch fieldType {
-	case "int":
+	case types.TypeInt:
 		intValue, err := strconv.Atoi(valueStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert value to int: %w", err)
 		}
 		return intValue, nil
-	case "bool":
+	case types.TypeBool:
 		boolValue, err := strconv.ParseBool(valueStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert value to bool: %w", err)
 		}
 		return boolValue, nil
-	case "float":
+	case types.TypeFloat:
 		floatValue, err := strconv.ParseFloat(valueStr, 64)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert value to float: %w", err)
 		}
 		return floatValue, nil
-	default: // "string"
+	case types.TypeDuration:
+		durationValue, err := time.ParseDuration(valueStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert value to duration: %w", err)
+		}
+		return durationValue, nil
+	case types.TypeStringList:
+		var sliceValue []string
+		if err := json.Unmarshal([]byte(valueStr), &sliceValue); err != nil {
+			return nil, fmt.Errorf("failed to convert value to []string: %w", err)
+		}
+		return sliceValue, nil
+	case types.TypeIntList:
+		var sliceValue []int
+		if err := json.Unmarshal([]byte(valueStr), &sliceValue); err != nil {
+			return nil, fmt.Errorf("failed to convert value to []int: %w", err)
+		}
+		return sliceValue, nil
+	case types.TypeStringMap:
+		var mapValue map[string]string
+		if err := json.Unmarshal([]byte(valueStr), &mapValue); err != nil {
+			return nil, fmt.Errorf("failed to convert value to map[string]string: %w", err)
+		}
+		return mapValue, nil
+	case types.TypeJSON:
+		if !json.Valid([]byte(valueStr)) {
+			return nil, fmt.Errorf("failed to convert value to json: invalid JSON")
+		}
+		return json.RawMessage(valueStr), nil
+	case types.TypeURL:
+		if _, err := parseURL(valueStr); err != nil {
+			return nil, err
+		}
+		return valueStr, nil
+	case types.TypeFlag:
+		return decodeFlag(valueStr)
+	default: // "string", "secret"
 		return valueStr, nil
 	}
 }
 
+// parseURL parses an absolute URL, which must have a scheme and a host.
+func parseURL(valueStr string) (*url.URL, error) {
+	urlValue, err := url.Parse(valueStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert value to url: %w", err)
+	}
+	if urlValue.Scheme == "" || urlValue.Host == "" {
+		return nil, fmt.Errorf("failed to convert value to url: %q is not an absolute URL", valueStr)
+	}
+	return urlValue, nil
+}
+
 // WatchConfig starts watching for changes to any key in the specified configuration namespace in the storage.
-// When a change is detected, it updates the corresponding key-value pair in the cache.
-// The method takes the schemaName, schemaVersion, and configName
-// to construct the base key for the configuration namespace.
+// When a change is detected, it updates the corresponding key-value pair in the cache
+// and calls the handlers registered with OnChange and OnAnyChange.
+// When a key is deleted, it is evicted from the cache so the next Get reads it from the storage.
+// Changes to the values of the config's parents, and to the parents themselves, are followed too:
+// they are reported as changes of the config whenever they change a value the config inherits.
+// If the schema version has not been added when watching starts, the config is watched all the same,
+// and handlers get the stored strings instead of typed values.
+// Watching stops when ctx is cancelled.
 func (r *Rigel) WatchConfig(ctx context.Context) error {
-	// Construct the base key for the configuration
-	baseKey := GetConfPath(r.App, r.Module, r.Version, r.Config)
+	// The schema gives handlers typed values; without one, they get the stored strings
+	schemaExists, err := r.SchemaExists(ctx)
+	if err != nil {
+		return err
+	}
+	var fields map[string]types.Field
+	if schemaExists {
+		schemaFields, err := r.getSchemaFields(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get schema: %w", err)
+		}
+		fields = make(map[string]types.Field, len(schemaFields))
+		for _, field := range schemaFields {
+			fields[field.Name] = field
+		}
+	}
 
+	// Watch every named config under the schema version, since any of them may be or become a parent.
+	// Start watching before loading the values, so that no change made in between is missed.
+	// The watch is stopped again if the values cannot be loaded, since nothing would read its events.
+	watchCtx, cancel := context.WithCancel(ctx)
 	events := make(chan types.Event)
-	if err := r.Storage.Watch(ctx, baseKey, events); err != nil {
+	if err := r.Storage.Watch(watchCtx, r.watchPrefix(), events); err != nil {
+		cancel()
+		return err
+	}
+	// IsEnabled keeps the schema it has read, until the schema changes
+	schemaKey := GetSchemaFieldsPath(r.App, r.Module, r.Version)
+	schemaEvents := make(chan types.Event)
+	if err := r.Storage.Watch(watchCtx, schemaKey, schemaEvents); err != nil {
+		cancel()
+		return err
+	}
+	go func() {
+		for range schemaEvents {
+			r.schemas.Delete(schemaKey)
+		}
+	}()
+
+	watch, err := r.newChainWatch(ctx)
+	if err != nil {
+		cancel()
 		return err
 	}
 
 	go func() {
+		defer cancel()
 		for event := range events {
-			// Only update keys in the cache that have changed
-			if _, found := r.Cache.Get(event.Key); found {
-				r.Cache.Set(event.Key, event.Value)
+			changes, err := watch.apply(ctx, event)
+			if err != nil {
+				// The parents could not be reloaded, so what is cached may be stale
+				for configKey := range fields {
+					r.Cache.Delete(GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey))
+				}
+				for configKey := range watch.values() {
+					r.Cache.Delete(GetConfKeyPath(r.App, r.Module, r.Version, r.Config, configKey))
+				}
+				continue
+			}
+			for _, change := range changes {
+				key := GetConfKeyPath(r.App, r.Module, r.Version, r.Config, change.Key)
+				if change.New == "" {
+					r.Cache.Delete(key)
+				} else if _, found := r.Cache.Get(key); found {
+					// Only update keys in the cache that have changed
+					r.Cache.Set(key, change.New)
+				}
+				r.notifyChange(change, fields)
 			}
 		}
 	}()
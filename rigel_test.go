@@ -2,11 +2,15 @@ package rigel
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/remiges-tech/rigel/etcd"
+	"github.com/remiges-tech/rigel/memstore"
 	"github.com/remiges-tech/rigel/mocks"
 	"github.com/remiges-tech/rigel/types"
 )
@@ -218,23 +222,26 @@ func TestAddSchema(t *testing.T) {
 
 	// Mocked Storage
 	mockStorage := &mocks.MockStorage{
-		PutFunc: func(ctx context.Context, key string, value string) error {
-			switch key {
-			case expectedFieldsKey:
-				if value != expectedFieldsValue {
-					t.Errorf("Expected fields value to be '%s', got '%s'", expectedFieldsValue, value)
-				}
-			case expectedDescriptionKey:
-				if value != expectedDescriptionValue {
-					t.Errorf("Expected description value to be '%s', got '%s'", expectedDescriptionValue, value)
-				}
-			case expectedFieldDescriptionKey:
-				// Expect the field description to be stored
-				if value != "" {
-					t.Errorf("Expected field description value to be empty, got '%s'", value)
-				}
-			default:
-				t.Errorf("Unexpected key: '%s'", key)
+		GetWithRevisionFunc: func(ctx context.Context, key string) (types.KeyValue, error) {
+			// The schema version does not exist yet
+			return types.KeyValue{}, nil
+		},
+		TxnFunc: func(ctx context.Context, ops []types.Op) error {
+			if len(ops) != 3 {
+				t.Fatalf("Expected 3 ops, got %d", len(ops))
+			}
+			if ops[0].Key != expectedFieldsKey || ops[0].Value != expectedFieldsValue {
+				t.Errorf("Expected fields value to be '%s', got '%s'", expectedFieldsValue, ops[0].Value)
+			}
+			if !ops[0].CheckRevision || ops[0].ModRevision != 0 {
+				t.Errorf("Expected the fields to be stored only if they do not exist yet")
+			}
+			if ops[1].Key != expectedDescriptionKey || ops[1].Value != expectedDescriptionValue {
+				t.Errorf("Expected description value to be '%s', got '%s'", expectedDescriptionValue, ops[1].Value)
+			}
+			// The field description is stored in the same transaction
+			if ops[2].Key != expectedFieldDescriptionKey || ops[2].Value != "" {
+				t.Errorf("Expected an empty field description at '%s', got '%s' at '%s'", expectedFieldDescriptionKey, ops[2].Value, ops[2].Key)
 			}
 			return nil
 		},
@@ -260,7 +267,10 @@ func TestAddSchemaWithTimeout(t *testing.T) {
 
 	// Mocked Storage
 	mockStorage := &mocks.MockStorage{
-		PutFunc: func(ctx context.Context, key string, value string) error {
+		GetWithRevisionFunc: func(ctx context.Context, key string) (types.KeyValue, error) {
+			return types.KeyValue{}, nil
+		},
+		TxnFunc: func(ctx context.Context, ops []types.Op) error {
 			// Simulate a delay with select to respect context timeout
 			select {
 			case <-time.After(2 * time.Second):
@@ -486,10 +496,20 @@ func TestSet_Success(t *testing.T) {
 			}
 			return "", fmt.Errorf("unexpected key: %s", key)
 		},
-		PutFunc: func(ctx context.Context, key string, value string) error {
-			// Check if the key and value are correct
-			if key != GetConfKeyPath("app", "module", 1, "config", "existingKey") || value != "value" {
-				return fmt.Errorf("unexpected key or value: %s, %s", key, value)
+		GetWithPrefixAndRevisionFunc: func(ctx context.Context, prefix string) (map[string]types.KeyValue, error) {
+			// The key has no value yet
+			return map[string]types.KeyValue{}, nil
+		},
+		TxnFunc: func(ctx context.Context, ops []types.Op) error {
+			// Check that the value is written along with its history entry
+			if len(ops) != 2 {
+				return fmt.Errorf("expected the value and a history entry, got %d ops", len(ops))
+			}
+			if ops[0].Key != GetConfKeyPath("app", "module", 1, "config", "existingKey") || ops[0].Value != "value" {
+				return fmt.Errorf("unexpected key or value: %s, %s", ops[0].Key, ops[0].Value)
+			}
+			if !strings.HasPrefix(ops[1].Key, GetConfHistoryPath("app", "module", 1, "config", "existingKey")+"/") {
+				return fmt.Errorf("unexpected history key: %s", ops[1].Key)
 			}
 			return nil
 		},
@@ -548,3 +568,648 @@ func ExampleRigel_LoadConfig() {
 	//// APIKey: abc123
 	//// IsDebug: false
 }
+
+func TestSetGetWithMemStorage(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "config")
+
+	if err := rigelClient.Set(ctx, "port", "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	port, err := rigelClient.GetInt(ctx, "port")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if port != 8080 {
+		t.Errorf("Expected 8080, got %d", port)
+	}
+}
+
+// newMemRigel returns a Rigel client backed by memstore with a simple schema already added.
+func newMemRigel(t *testing.T, config string) *Rigel {
+	t.Helper()
+	rigelClient := New(memstore.New(), "app", "module", 1, config)
+	schema := types.Schema{
+		Fields: []types.Field{
+			{Name: "host", Type: "string"},
+			{Name: "port", Type: "int"},
+		},
+		Description: "description",
+		Version:     1,
+	}
+	if err := rigelClient.AddSchema(context.Background(), schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return rigelClient
+}
+
+func TestDeleteKey(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "config")
+
+	if err := rigelClient.Set(ctx, "host", "localhost"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.DeleteKey(ctx, "host"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The cached value must be gone as well
+	value, err := rigelClient.Get(ctx, "host")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "" {
+		t.Errorf("Expected empty value after delete, got '%s'", value)
+	}
+
+	err = rigelClient.DeleteKey(ctx, "nonExistingKey")
+	if _, ok := err.(*KeyNotFoundError); !ok {
+		t.Errorf("Expected error to be a KeyNotFoundError, got %T", err)
+	}
+}
+
+func TestDeleteConfig(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "prod")
+
+	if err := rigelClient.Set(ctx, "host", "localhost"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.WithConfig("prod-eu").Set(ctx, "host", "eu.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := rigelClient.WithConfig("prod").DeleteConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	configs, err := rigelClient.ListConfigs(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(configs) != 1 || configs[0] != "prod-eu" {
+		t.Errorf("Expected only 'prod-eu' to remain, got %v", configs)
+	}
+}
+
+func TestDeleteSchema(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "prod")
+
+	if err := rigelClient.Set(ctx, "host", "localhost"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Named configs exist, so a plain delete is refused
+	err := rigelClient.DeleteSchema(ctx, false)
+	inUse, ok := err.(*SchemaInUseError)
+	if !ok {
+		t.Fatalf("Expected error to be a SchemaInUseError, got %T", err)
+	}
+	if len(inUse.Configs) != 1 || inUse.Configs[0] != "prod" {
+		t.Errorf("Expected configs [prod], got %v", inUse.Configs)
+	}
+
+	if err := rigelClient.DeleteSchema(ctx, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	keyVal, err := rigelClient.Storage.GetWithPrefix(ctx, GetSchemaPath("app", "module", 1))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 0 {
+		t.Errorf("Expected schema to be deleted, got %v", keyVal)
+	}
+}
+
+func TestWatchConfigEvictsDeletedKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rigelClient := newMemRigel(t, "config")
+
+	if err := rigelClient.Set(ctx, "host", "localhost"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.WatchConfig(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	key := GetConfKeyPath("app", "module", 1, "config", "host")
+	if err := rigelClient.Storage.Delete(ctx, key); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, found := rigelClient.Cache.Get(key); !found {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected deleted key to be evicted from the cache")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newMemRigelWithDefaults returns a Rigel client backed by memstore with a schema that uses defaults and required fields.
+func newMemRigelWithDefaults(t *testing.T) *Rigel {
+	t.Helper()
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+	schema := types.Schema{
+		Fields: []types.Field{
+			{Name: "host", Type: "string", Required: true},
+			{Name: "port", Type: "int", Default: float64(8080)}, // numbers decoded from JSON are float64
+			{Name: "debug", Type: "bool", Default: false},
+			{Name: "logLevel", Type: "string", Required: true, Default: "info"},
+			{Name: "timeout", Type: "int"},
+		},
+		Description: "description",
+		Version:     1,
+	}
+	if err := rigelClient.AddSchema(context.Background(), schema); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return rigelClient
+}
+
+func TestAddSchemaRejectsInvalidDefault(t *testing.T) {
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+	min := 1.0
+	schema := types.Schema{
+		Fields: []types.Field{
+			{Name: "port", Type: "int", Default: float64(0), Constraints: &types.Constraints{Min: &min}},
+		},
+		Version: 1,
+	}
+	if err := rigelClient.AddSchema(context.Background(), schema); err == nil {
+		t.Errorf("Expected error for a default that violates the constraints, got nil")
+	}
+}
+
+func TestCreateConfig(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigelWithDefaults(t)
+
+	// host is required and has no default
+	err := rigelClient.CreateConfig(ctx, rigelClient.Config, "", map[string]string{"port": "9090"})
+	var missing *MissingRequiredFieldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingRequiredFieldsError, got %v", err)
+	}
+	if len(missing.Fields) != 1 || missing.Fields[0] != "host" {
+		t.Errorf("Expected missing fields [host], got %v", missing.Fields)
+	}
+
+	// Set cannot create the config either
+	err = rigelClient.Set(ctx, "port", "9090")
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingRequiredFieldsError from Set, got %v", err)
+	}
+
+	if err := rigelClient.CreateConfig(ctx, rigelClient.Config, "local development", map[string]string{"host": "localhost"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The description is kept apart from the config keys
+	if description, err := rigelClient.ConfigDescription(ctx); err != nil || description != "local development" {
+		t.Errorf("Expected description %q, got %q (err %v)", "local development", description, err)
+	}
+	keys, err := rigelClient.Storage.GetWithPrefix(ctx, GetConfKeyPath(rigelClient.App, rigelClient.Module, rigelClient.Version, rigelClient.Config, ""))
+	if err != nil || len(keys) != 1 {
+		t.Errorf("Expected only host under the config keys, got %v (err %v)", keys, err)
+	}
+
+	// Once the config exists, single keys can be set
+	if err := rigelClient.Set(ctx, "port", "9090"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A config can be created empty
+	empty := newMemRigel(t, "empty")
+	if err := empty.CreateConfig(ctx, "empty", "", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if configs, err := empty.ListConfigs(ctx); err != nil || len(configs) != 1 || configs[0] != "empty" {
+		t.Errorf("Expected the empty config to be listed, got %v (err %v)", configs, err)
+	}
+
+	var exists *ConfigExistsError
+	err = rigelClient.CreateConfig(ctx, rigelClient.Config, "", map[string]string{"host": "localhost"})
+	if !errors.As(err, &exists) {
+		t.Errorf("Expected a ConfigExistsError, got %v", err)
+	}
+}
+
+func TestGetDefault(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigelWithDefaults(t)
+	if err := rigelClient.CreateConfig(ctx, rigelClient.Config, "", map[string]string{"host": "localhost"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	port, err := rigelClient.GetInt(ctx, "port")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if port != 8080 {
+		t.Errorf("Expected default 8080, got %d", port)
+	}
+
+	logLevel, err := rigelClient.Get(ctx, "logLevel")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if logLevel != "info" {
+		t.Errorf("Expected default 'info', got '%s'", logLevel)
+	}
+
+	// An empty value is no value: the same client and a fresh one both return the default
+	if err := rigelClient.Set(ctx, "logLevel", "debug"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.Set(ctx, "logLevel", ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	fresh := New(rigelClient.Storage, rigelClient.App, rigelClient.Module, rigelClient.Version, rigelClient.Config)
+	for _, client := range []*Rigel{rigelClient, fresh} {
+		if logLevel, err := client.Get(ctx, "logLevel"); err != nil || logLevel != "info" {
+			t.Errorf("Expected default 'info' after setting an empty value, got '%s' (err %v)", logLevel, err)
+		}
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigelWithDefaults(t)
+	if err := rigelClient.CreateConfig(ctx, rigelClient.Config, "", map[string]string{"host": "localhost", "debug": "true"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config := struct {
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Debug    bool   `json:"debug"`
+		LogLevel string `json:"logLevel"`
+		Timeout  int    `json:"timeout"`
+	}{Timeout: 30}
+	if err := rigelClient.LoadConfig(ctx, &config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Host != "localhost" || config.Port != 8080 || !config.Debug || config.LogLevel != "info" {
+		t.Errorf("Expected stored values and defaults, got %+v", config)
+	}
+	// A field with no value and no default leaves the struct untouched
+	if config.Timeout != 30 {
+		t.Errorf("Expected timeout to keep its value 30, got %d", config.Timeout)
+	}
+}
+
+func TestLoadConfigMissingRequired(t *testing.T) {
+	rigelClient := newMemRigelWithDefaults(t)
+
+	var config struct {
+		Host string `json:"host"`
+	}
+	err := rigelClient.LoadConfig(context.Background(), &config)
+	var missing *MissingRequiredFieldsError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a MissingRequiredFieldsError, got %v", err)
+	}
+}
+
+// racingCreateStorage creates the config named config, as another caller would, just before the first transaction.
+type racingCreateStorage struct {
+	types.Storage
+	config string
+	txns   int
+}
+
+func (s *racingCreateStorage) Txn(ctx context.Context, ops []types.Op) error {
+	s.txns++
+	if s.txns == 1 {
+		if err := s.Storage.Put(ctx, GetConfCreatedPath("app", "module", 1, s.config), "2024-01-01T00:00:00Z"); err != nil {
+			return err
+		}
+	}
+	return s.Storage.Txn(ctx, ops)
+}
+
+func TestCreateConfigRace(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "config")
+	storage := &racingCreateStorage{Storage: rigelClient.Storage, config: "config"}
+	rigelClient.Storage = storage
+
+	// The other caller's config is not written over, and the write is not retried
+	var exists *ConfigExistsError
+	if err := rigelClient.CreateConfig(ctx, "config", "", nil); !errors.As(err, &exists) {
+		t.Fatalf("Expected a ConfigExistsError, got %v", err)
+	}
+	if storage.txns != 1 {
+		t.Errorf("Expected 1 transaction, got %d", storage.txns)
+	}
+}
+
+// newMemRigelWithRichTypes returns a Rigel client backed by memstore whose schema uses the
+// duration, list, map, json, url and secret types, with a value stored for each field.
+func newMemRigelWithRichTypes(t *testing.T) *Rigel {
+	t.Helper()
+	ctx := context.Background()
+	rigelClient := New(memstore.New(), "app", "module", 1, "config")
+	schema := types.Schema{
+		Fields: []types.Field{
+			{Name: "timeout", Type: types.TypeDuration},
+			{Name: "hosts", Type: types.TypeStringList},
+			{Name: "ports", Type: types.TypeIntList},
+			{Name: "labels", Type: types.TypeStringMap},
+			{Name: "limits", Type: types.TypeJSON},
+			{Name: "endpoint", Type: types.TypeURL},
+			{Name: "password", Type: types.TypeSecret},
+		},
+		Version: 1,
+	}
+	if err := rigelClient.AddSchema(ctx, schema); err != nil {
+		t.Fatalf("Expected no error adding schema, got %v", err)
+	}
+	values := map[string]string{
+		"timeout":  "1m30s",
+		"hosts":    `["a.example.com","b.example.com"]`,
+		"ports":    `[80,443]`,
+		"labels":   `{"env":"prod"}`,
+		"limits":   `{"rps":100}`,
+		"endpoint": "https://example.com/api",
+		"password": "s3cret",
+	}
+	if err := rigelClient.CreateConfig(ctx, rigelClient.Config, "", values); err != nil {
+		t.Fatalf("Expected no error creating config, got %v", err)
+	}
+	return rigelClient
+}
+
+func TestRichTypeGetters(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigelWithRichTypes(t)
+
+	timeout, err := rigelClient.GetDuration(ctx, "timeout")
+	if err != nil || timeout != 90*time.Second {
+		t.Errorf("Expected 1m30s, got %v (err %v)", timeout, err)
+	}
+
+	hosts, err := rigelClient.GetStringSlice(ctx, "hosts")
+	if err != nil || !reflect.DeepEqual(hosts, []string{"a.example.com", "b.example.com"}) {
+		t.Errorf("Expected both hosts, got %v (err %v)", hosts, err)
+	}
+
+	ports, err := rigelClient.GetIntSlice(ctx, "ports")
+	if err != nil || !reflect.DeepEqual(ports, []int{80, 443}) {
+		t.Errorf("Expected [80 443], got %v (err %v)", ports, err)
+	}
+
+	labels, err := rigelClient.GetStringMap(ctx, "labels")
+	if err != nil || labels["env"] != "prod" {
+		t.Errorf("Expected env=prod, got %v (err %v)", labels, err)
+	}
+
+	var limits struct {
+		RPS int `json:"rps"`
+	}
+	if err := rigelClient.GetJSON(ctx, "limits", &limits); err != nil || limits.RPS != 100 {
+		t.Errorf("Expected rps 100, got %d (err %v)", limits.RPS, err)
+	}
+
+	endpoint, err := rigelClient.GetURL(ctx, "endpoint")
+	if err != nil || endpoint.Host != "example.com" || endpoint.Path != "/api" {
+		t.Errorf("Expected https://example.com/api, got %v (err %v)", endpoint, err)
+	}
+
+	// A value that does not match the field type is rejected
+	if err := rigelClient.Set(ctx, "timeout", "90"); err == nil {
+		t.Errorf("Expected error setting an invalid duration, got nil")
+	}
+}
+
+func TestLoadConfigRichTypes(t *testing.T) {
+	rigelClient := newMemRigelWithRichTypes(t)
+
+	var config struct {
+		Timeout time.Duration     `json:"timeout"`
+		Hosts   []string          `json:"hosts"`
+		Ports   []int             `json:"ports"`
+		Labels  map[string]string `json:"labels"`
+		Limits  struct {
+			RPS int `json:"rps"`
+		} `json:"limits"`
+		Endpoint string `json:"endpoint"`
+		Password string `json:"password"`
+	}
+	if err := rigelClient.LoadConfig(context.Background(), &config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Timeout != 90*time.Second || len(config.Hosts) != 2 || len(config.Ports) != 2 ||
+		config.Labels["env"] != "prod" || config.Limits.RPS != 100 ||
+		config.Endpoint != "https://example.com/api" || config.Password != "s3cret" {
+		t.Errorf("Expected all rich type values to be loaded, got %+v", config)
+	}
+}
+
+func TestSetValidationError(t *testing.T) {
+	rigelClient := newMemRigel(t, "config")
+
+	err := rigelClient.Set(context.Background(), "port", "not-a-number")
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	if invalid.Field != "port" || invalid.Constraint != ConstraintType || invalid.Value != "not-a-number" {
+		t.Errorf("Expected a type error for port, got %+v", *invalid)
+	}
+}
+
+func TestCreateConfigValidationErrors(t *testing.T) {
+	rigelClient := newMemRigelWithDefaults(t)
+
+	err := rigelClient.CreateConfig(context.Background(), rigelClient.Config, "", map[string]string{"host": "localhost", "port": "abc", "debug": "maybe"})
+	var invalid ValidationErrors
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	if len(invalid) != 2 || invalid[0].Field != "debug" || invalid[1].Field != "port" {
+		t.Errorf("Expected errors for debug and port in key order, got %v", invalid)
+	}
+
+	exists, err := rigelClient.ConfigExists(context.Background())
+	if err != nil || exists {
+		t.Errorf("Expected the config not to be created, got exists %v (err %v)", exists, err)
+	}
+}
+
+func TestSetMany(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "config")
+
+	if err := rigelClient.SetMany(ctx, map[string]string{"host": "example.com", "port": "8080"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	host, err := rigelClient.Get(ctx, "host")
+	if err != nil || host != "example.com" {
+		t.Errorf("Expected host 'example.com', got '%s' (err %v)", host, err)
+	}
+
+	// One invalid value means nothing is written
+	err = rigelClient.SetMany(ctx, map[string]string{"host": "other.example.com", "port": "abc"})
+	var invalid ValidationErrors
+	if !errors.As(err, &invalid) || len(invalid) != 1 || invalid[0].Field != "port" {
+		t.Fatalf("Expected a ValidationErrors for port, got %v", err)
+	}
+	host, err = rigelClient.Get(ctx, "host")
+	if err != nil || host != "example.com" {
+		t.Errorf("Expected host to stay 'example.com', got '%s' (err %v)", host, err)
+	}
+
+	var notFound *KeyNotFoundError
+	if err := rigelClient.SetMany(ctx, map[string]string{"unknown": "value"}); !errors.As(err, &notFound) {
+		t.Errorf("Expected a KeyNotFoundError, got %v", err)
+	}
+}
+
+func TestSetManyMissingRequired(t *testing.T) {
+	rigelClient := newMemRigelWithDefaults(t)
+
+	err := rigelClient.SetMany(context.Background(), map[string]string{"port": "9090"})
+	var missing *MissingRequiredFieldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingRequiredFieldsError, got %v", err)
+	}
+
+	if err := rigelClient.SetMany(context.Background(), map[string]string{"host": "localhost", "port": "9090"}); err != nil {
+		t.Errorf("Expected the config to be created, got %v", err)
+	}
+}
+
+func TestSetExpectRevision(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "config")
+
+	// A key that has no value yet has revision 0
+	_, rev, err := rigelClient.GetWithRevision(ctx, "host")
+	if err != nil || rev != 0 {
+		t.Fatalf("Expected revision 0, got %d (err %v)", rev, err)
+	}
+	if err := rigelClient.Set(ctx, "host", "first.example.com", ExpectRevision(rev)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	value, rev, err := rigelClient.GetWithRevision(ctx, "host")
+	if err != nil || value != "first.example.com" || rev == 0 {
+		t.Fatalf("Expected the stored value with a revision, got '%s' at %d (err %v)", value, rev, err)
+	}
+
+	// Another writer changes the value after it was read
+	if err := rigelClient.Set(ctx, "host", "second.example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = rigelClient.Set(ctx, "host", "third.example.com", ExpectRevision(rev))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a *ConflictError, got %v", err)
+	}
+	if conflict.Key != "host" || conflict.ExpectedRevision != rev || conflict.ModRevision <= rev {
+		t.Errorf("Expected a conflict on host newer than %d, got %+v", rev, *conflict)
+	}
+
+	value, _, err = rigelClient.GetWithRevision(ctx, "host")
+	if err != nil || value != "second.example.com" {
+		t.Errorf("Expected host to stay 'second.example.com', got '%s' (err %v)", value, err)
+	}
+}
+
+func TestSetManyExpectRevisions(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigel(t, "config")
+	if err := rigelClient.SetMany(ctx, map[string]string{"host": "example.com", "port": "8080"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, hostRev, _ := rigelClient.GetWithRevision(ctx, "host")
+	_, portRev, _ := rigelClient.GetWithRevision(ctx, "port")
+
+	if err := rigelClient.Set(ctx, "port", "9090"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err := rigelClient.SetMany(ctx, map[string]string{"host": "other.example.com", "port": "7070"},
+		ExpectRevisions(map[string]int64{"host": hostRev, "port": portRev}))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Key != "port" {
+		t.Fatalf("Expected a *ConflictError on port, got %v", err)
+	}
+
+	host, _, _ := rigelClient.GetWithRevision(ctx, "host")
+	if host != "example.com" {
+		t.Errorf("Expected host to stay 'example.com', got '%s'", host)
+	}
+}
+
+func TestAddSchemaImmutable(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := newMemRigelWithDefaults(t)
+	schema, err := rigelClient.GetSchema(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	schema.Version = 1
+
+	// Re-adding the same schema is a no-op, even with an int default instead of the stored float64
+	schema.Fields[1].Default = 8080
+	if err := rigelClient.AddSchema(ctx, *schema); err != nil {
+		t.Fatalf("Expected re-adding an identical schema to succeed, got %v", err)
+	}
+
+	// A changed schema is rejected with the differences
+	changed := *schema
+	changed.Fields = append([]types.Field{}, schema.Fields...)
+	changed.Fields[4].Type = types.TypeString                                          // timeout
+	changed.Fields = append(changed.Fields[:2], changed.Fields[3:]...)                 // drop debug
+	changed.Fields = append(changed.Fields, types.Field{Name: "retries", Type: "int"}) // add retries
+	var changedErr *SchemaChangedError
+	if err := rigelClient.AddSchema(ctx, changed); !errors.As(err, &changedErr) {
+		t.Fatalf("Expected a SchemaChangedError, got %v", err)
+	}
+	diff := changedErr.Diff
+	if len(diff.Added) != 1 || diff.Added[0].Name != "retries" || len(diff.Removed) != 1 || diff.Removed[0].Name != "debug" ||
+		len(diff.Changed) != 1 || diff.Changed[0].Name != "timeout" {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+
+	// Forcing is refused while a named config would become invalid
+	if err := rigelClient.SetMany(ctx, map[string]string{"host": "localhost", "debug": "true"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var incompatible *SchemaIncompatibleError
+	if err := rigelClient.AddSchema(ctx, changed, ForceOverwrite()); !errors.As(err, &incompatible) || len(incompatible.Configs["config"]) != 1 {
+		t.Fatalf("Expected a SchemaIncompatibleError for config, got %v", err)
+	}
+
+	// and allowed once it would not
+	if err := rigelClient.DeleteKey(ctx, "debug"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.AddSchema(ctx, changed, ForceOverwrite()); err != nil {
+		t.Fatalf("Expected the forced overwrite to succeed, got %v", err)
+	}
+	stored, err := rigelClient.GetSchema(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if field := findField(stored.Fields, "timeout"); field == nil || field.Type != types.TypeString || findField(stored.Fields, "debug") != nil {
+		t.Errorf("Expected the new fields to be stored, got %+v", stored.Fields)
+	}
+	if descr, _ := rigelClient.Storage.GetWithPrefix(ctx, GetSchemaFieldsPath("app", "module", 1)+"/debug"); len(descr) != 0 {
+		t.Errorf("Expected the description of the removed field to be deleted, got %v", descr)
+	}
+}
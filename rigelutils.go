@@ -1,7 +1,16 @@
 package rigel
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/remiges-tech/rigel/types"
 )
@@ -33,62 +42,345 @@ func GetConfKeyPath(appName string, moduleName string, version int, namedConfig
 
 }
 
+// GetConfDescriptionPath constructs the path of the description of a named config. Like the rest of a
+// named config's metadata it is kept under meta/, apart from the config keys, so that it cannot clash
+// with a schema field named description.
+func GetConfDescriptionPath(appName string, moduleName string, version int, namedConfig string) string {
+	return fmt.Sprintf("%s/%s/%s/%d/config/%s/meta/%s", rigelPrefix, appName, moduleName, version, namedConfig, configDescriptionKey)
+}
+
+// GetConfParentPath constructs the path of the name of the parent of a named config, the config it inherits
+// the values it does not set from.
+func GetConfParentPath(appName string, moduleName string, version int, namedConfig string) string {
+	return fmt.Sprintf("%s/%s/%s/%d/config/%s/meta/%s", rigelPrefix, appName, moduleName, version, namedConfig, configParentKey)
+}
+
+// GetConfCreatedPath constructs the path of the time a named config was created at. It marks the config
+// as existing even when it has no values or other metadata.
+func GetConfCreatedPath(appName string, moduleName string, version int, namedConfig string) string {
+	return fmt.Sprintf("%s/%s/%s/%d/config/%s/meta/%s", rigelPrefix, appName, moduleName, version, namedConfig, configCreatedKey)
+}
+
+// GetConfHistoryPath constructs the path under which the history of a config key is kept.
+// With an empty confKey, it returns the path of the history of the whole named config.
+func GetConfHistoryPath(appName string, moduleName string, version int, namedConfig string, confKey string) string {
+	return fmt.Sprintf("%s/%s/%s/%d/config/%s/history/%s", rigelPrefix, appName, moduleName, version, namedConfig, confKey)
+}
+
+// IsConfigDataKey reports whether sub, the part of a key after a named config's path and slash, is one of
+// the config's values or its metadata. Only those make a named config exist; its history is kept after
+// its values are gone.
+func IsConfigDataKey(sub string) bool {
+	return strings.HasPrefix(sub, "keys/") || strings.HasPrefix(sub, "meta/")
+}
+
+// ValidateValueAgainstConstraints checks that value can be converted to the field's type and meets its constraints.
+// See types.Constraints for how each constraint applies to each type. Use ValidateValue to find out which check failed.
 func ValidateValueAgainstConstraints(value string, field *types.Field) bool {
+	return ValidateValue(value, field) == nil
+}
+
+// ValidateValue checks that value can be converted to the field's type and meets its constraints.
+// It returns a *ValidationError for the first check that fails, or nil if the value is valid.
+func ValidateValue(value string, field *types.Field) error {
 	// Convert the value to the correct type
 	val, err := convertToType(value, field.Type)
 	if err != nil {
-		return false
+		return newValidationError(field, ConstraintType, value, field.Type)
+	}
+
+	// The rules of a flag must be well formed
+	if flag, ok := val.(Flag); ok {
+		if err := flag.Validate(); err != nil {
+			return newValidationError(field, ConstraintRules, value, err.Error())
+		}
+	}
+
+	c := field.Constraints
+	if c == nil {
+		return nil
 	}
 
 	// Check the constraints
-	if field.Constraints != nil {
-		if field.Constraints.Min != nil {
-			switch field.Type {
-			case "int":
-				if val.(int) < *field.Constraints.Min {
-					return false
-				}
-			case "string":
-				if len(val.(string)) < *field.Constraints.Min {
-					return false
-				}
-			case "float":
-				if val.(float64) < float64(*field.Constraints.Min) {
-					return false
-				}
+	if n, ok := numericValue(val); ok {
+		if constraint, limit := checkBounds(n, c.Min, c.Max, c.ExclusiveMin, c.ExclusiveMax); constraint != "" {
+			return newValidationError(field, constraint, value, formatNumber(limit))
+		}
+		if !isMultipleOf(n, c.MultipleOf) {
+			return newValidationError(field, ConstraintMultipleOf, value, formatNumber(*c.MultipleOf))
+		}
+	} else if length, ok := lengthOf(val); ok {
+		constraint, limit := checkBounds(float64(length), c.Min, c.Max, false, false)
+		if c.MinLength != nil || c.MaxLength != nil {
+			constraint, limit = checkBounds(float64(length), intToFloat(c.MinLength), intToFloat(c.MaxLength), false, false)
+			switch constraint {
+			case ConstraintMin:
+				constraint = ConstraintMinLength
+			case ConstraintMax:
+				constraint = ConstraintMaxLength
 			}
 		}
-		if field.Constraints.Max != nil {
-			switch field.Type {
-			case "int":
-				if val.(int) > *field.Constraints.Max {
-					return false
-				}
-			case "string":
-				if len(val.(string)) > *field.Constraints.Max {
-					return false
-				}
-			case "float":
-				if val.(float64) > float64(*field.Constraints.Max) {
-					return false
+		if constraint != "" {
+			return newValidationError(field, constraint, value, formatNumber(limit))
+		}
+	}
+
+	if c.Pattern != "" {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return newValidationError(field, ConstraintPattern, value, c.Pattern)
+		}
+		switch v := val.(type) {
+		case string:
+			if !re.MatchString(v) {
+				return newValidationError(field, ConstraintPattern, value, c.Pattern)
+			}
+		case []string:
+			for _, elem := range v {
+				if !re.MatchString(elem) {
+					return newValidationError(field, ConstraintPattern, value, c.Pattern)
 				}
 			}
 		}
-		if field.Constraints.Enum != nil {
-			if field.Type == "string" {
-				found := false
-				for _, v := range field.Constraints.Enum {
-					if v == val.(string) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					return false
-				}
+	}
+
+	if c.Enum != nil {
+		valid := true
+		switch v := val.(type) {
+		case []string:
+			for _, elem := range v {
+				valid = valid && enumContains(c.Enum, types.TypeString, elem)
+			}
+		case []int:
+			for _, elem := range v {
+				valid = valid && enumContains(c.Enum, types.TypeInt, elem)
+			}
+		case map[string]string, json.RawMessage, Flag:
+		default:
+			valid = enumContains(c.Enum, field.Type, v)
+		}
+		if !valid {
+			return newValidationError(field, ConstraintEnum, value, strings.Join(c.Enum, ", "))
+		}
+	}
+
+	return nil
+}
+
+// newValidationError returns a *ValidationError for field. The value of secret fields is left out.
+func newValidationError(field *types.Field, constraint, value, limit string) *ValidationError {
+	if field.Type == types.TypeSecret {
+		value = ""
+	}
+	return &ValidationError{Field: field.Name, Constraint: constraint, Value: value, Limit: limit}
+}
+
+// numericValue returns the value of int, float and duration values as a float64, with durations in seconds.
+func numericValue(val any) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case time.Duration:
+		return v.Seconds(), true
+	}
+	return 0, false
+}
+
+// lengthOf returns the number of characters of string values and the number of elements of list and map values.
+func lengthOf(val any) (int, bool) {
+	switch v := val.(type) {
+	case string:
+		return utf8.RuneCountInString(v), true
+	case []string, []int, map[string]string:
+		return reflect.ValueOf(v).Len(), true
+	}
+	return 0, false
+}
+
+// checkBounds checks n against min and max, either of which may be nil. If n is out of bounds it returns
+// the constraint that failed and its limit; otherwise it returns an empty constraint.
+func checkBounds(n float64, min, max *float64, exclusiveMin, exclusiveMax bool) (string, float64) {
+	switch {
+	case min != nil && n < *min:
+		return ConstraintMin, *min
+	case min != nil && exclusiveMin && n == *min:
+		return ConstraintExclusiveMin, *min
+	case max != nil && n > *max:
+		return ConstraintMax, *max
+	case max != nil && exclusiveMax && n == *max:
+		return ConstraintExclusiveMax, *max
+	}
+	return "", 0
+}
+
+// formatNumber formats a constraint limit without a trailing fraction for whole numbers.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// isMultipleOf reports whether n is a multiple of m, allowing for floating point rounding. A nil m is not checked.
+func isMultipleOf(n float64, m *float64) bool {
+	if m == nil {
+		return true
+	}
+	q := n / *m
+	return math.Abs(q-math.Round(q)) < 1e-9
+}
+
+// enumContains reports whether one of the enum entries, converted to typ, equals val.
+func enumContains(enum []string, typ string, val any) bool {
+	for _, entry := range enum {
+		v, err := convertToType(entry, typ)
+		if err == nil && v == val {
+			return true
+		}
+	}
+	return false
+}
+
+func intToFloat(i *int) *float64 {
+	if i == nil {
+		return nil
+	}
+	f := float64(*i)
+	return &f
+}
+
+// validateConstraints checks that the constraints of a field are well formed.
+func validateConstraints(field *types.Field) error {
+	c := field.Constraints
+	if c == nil {
+		return nil
+	}
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		return fmt.Errorf("min of field %s is greater than its max", field.Name)
+	}
+	if c.MinLength != nil && c.MaxLength != nil && *c.MinLength > *c.MaxLength {
+		return fmt.Errorf("minLength of field %s is greater than its maxLength", field.Name)
+	}
+	if (c.MinLength != nil && *c.MinLength < 0) || (c.MaxLength != nil && *c.MaxLength < 0) {
+		return fmt.Errorf("minLength and maxLength of field %s must not be negative", field.Name)
+	}
+	if c.MultipleOf != nil && *c.MultipleOf <= 0 {
+		return fmt.Errorf("multipleOf of field %s must be greater than zero", field.Name)
+	}
+	if c.Pattern != "" {
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return fmt.Errorf("pattern of field %s is not a valid regular expression: %w", field.Name, err)
+		}
+	}
+
+	enumType := field.Type
+	switch field.Type {
+	case types.TypeStringList:
+		enumType = types.TypeString
+	case types.TypeIntList:
+		enumType = types.TypeInt
+	}
+	for _, entry := range c.Enum {
+		if _, err := convertToType(entry, enumType); err != nil {
+			return fmt.Errorf("enum value %q of field %s is not a valid %s: %w", entry, field.Name, enumType, err)
+		}
+	}
+	return nil
+}
+
+// DefaultValue returns the default value of the field in the string form it would be stored in,
+// and whether the field has a default at all.
+func DefaultValue(field *types.Field) (string, bool) {
+	switch v := field.Default.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case bool, int, int64, float64, time.Duration:
+		return fmt.Sprint(v), true
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v), true
+		}
+		return string(b), true
+	}
+}
+
+// ValidateFields checks the field definitions of a schema.
+// Every field name must be unique, constraints must be well formed, and a default value must be valid
+// for the field's type and constraints.
+func ValidateFields(fields []types.Field) error {
+	seen := make(map[string]bool, len(fields))
+	for i := range fields {
+		field := &fields[i]
+		if seen[field.Name] {
+			return fmt.Errorf("field %s is defined more than once", field.Name)
+		}
+		seen[field.Name] = true
+
+		if err := validateConstraints(field); err != nil {
+			return err
+		}
+		if def, ok := DefaultValue(field); ok {
+			if err := ValidateValue(def, field); err != nil {
+				return fmt.Errorf("invalid default value: %w", err)
 			}
 		}
 	}
+	return nil
+}
 
-	return true
+// validateValues checks values, keyed by config key, against fields. Invalid values are reported
+// together, in key order, in a ValidationErrors.
+func validateValues(fields []types.Field, values map[string]string) error {
+	var errs ValidationErrors
+	for _, configKey := range sortedKeys(values) {
+		field := findField(fields, configKey)
+		if field == nil {
+			return &KeyNotFoundError{Key: configKey}
+		}
+		if err := ValidateValue(values[configKey], field); err != nil {
+			errs = append(errs, err.(*ValidationError))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of values in lexical order.
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// missingRequiredFields returns the names of the required fields that have neither a value in values nor a default.
+func missingRequiredFields(fields []types.Field, values map[string]string) []string {
+	var missing []string
+	for i := range fields {
+		field := &fields[i]
+		if !field.Required || values[field.Name] != "" {
+			continue
+		}
+		if _, ok := DefaultValue(field); ok {
+			continue
+		}
+		missing = append(missing, field.Name)
+	}
+	return missing
+}
+
+// findField returns the field with the given name, or nil if the schema has no such field.
+func findField(fields []types.Field, name string) *types.Field {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
 }
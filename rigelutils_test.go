@@ -1,6 +1,7 @@
 package rigel
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/remiges-tech/rigel/types"
@@ -112,8 +113,8 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 			field: types.Field{
 				Type: "int",
 				Constraints: &types.Constraints{
-					Min: new(int),
-					Max: new(int),
+					Min: new(float64),
+					Max: new(float64),
 				},
 			},
 			expected: true,
@@ -124,8 +125,8 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 			field: types.Field{
 				Type: "int",
 				Constraints: &types.Constraints{
-					Min: new(int),
-					Max: new(int),
+					Min: new(float64),
+					Max: new(float64),
 				},
 			},
 			expected: false,
@@ -136,8 +137,8 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 			field: types.Field{
 				Type: "float",
 				Constraints: &types.Constraints{
-					Min: new(int),
-					Max: new(int),
+					Min: new(float64),
+					Max: new(float64),
 				},
 			},
 			expected: true,
@@ -148,8 +149,8 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 			field: types.Field{
 				Type: "float",
 				Constraints: &types.Constraints{
-					Min: new(int),
-					Max: new(int),
+					Min: new(float64),
+					Max: new(float64),
 				},
 			},
 			expected: false,
@@ -160,8 +161,8 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 			field: types.Field{
 				Type: "string",
 				Constraints: &types.Constraints{
-					Min: new(int),
-					Max: new(int),
+					Min: new(float64),
+					Max: new(float64),
 				},
 			},
 			expected: true,
@@ -172,8 +173,8 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 			field: types.Field{
 				Type: "string",
 				Constraints: &types.Constraints{
-					Min: new(int),
-					Max: new(int),
+					Min: new(float64),
+					Max: new(float64),
 				},
 			},
 			expected: false,
@@ -214,3 +215,177 @@ func TestValidateValueAgainstConstraints(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    types.Field
+		expected string
+		ok       bool
+	}{
+		{"no default", types.Field{Type: "string"}, "", false},
+		{"string default", types.Field{Type: "string", Default: "info"}, "info", true},
+		{"empty string default", types.Field{Type: "string", Default: ""}, "", true},
+		{"number from JSON", types.Field{Type: "int", Default: float64(8080)}, "8080", true},
+		{"float default", types.Field{Type: "float", Default: 0.5}, "0.5", true},
+		{"bool default", types.Field{Type: "bool", Default: true}, "true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DefaultValue(&tt.field)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("DefaultValue() = %q, %v, want %q, %v", got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	max, zero := 10.0, 0.0
+	tests := []struct {
+		name    string
+		fields  []types.Field
+		wantErr bool
+	}{
+		{"valid default", []types.Field{{Name: "port", Type: "int", Default: float64(5)}}, false},
+		{"default of wrong type", []types.Field{{Name: "port", Type: "int", Default: "abc"}}, true},
+		{"default out of range", []types.Field{{Name: "port", Type: "int", Default: float64(11), Constraints: &types.Constraints{Max: &max}}}, true},
+		{"duplicate field", []types.Field{{Name: "port", Type: "int"}, {Name: "port", Type: "string"}}, true},
+		{"invalid pattern", []types.Field{{Name: "host", Type: "string", Constraints: &types.Constraints{Pattern: "a("}}}, true},
+		{"min greater than max", []types.Field{{Name: "port", Type: "int", Constraints: &types.Constraints{Min: &max, Max: &zero}}}, true},
+		{"non-positive multipleOf", []types.Field{{Name: "port", Type: "int", Constraints: &types.Constraints{MultipleOf: &zero}}}, true},
+		{"enum value of wrong type", []types.Field{{Name: "port", Type: "int", Constraints: &types.Constraints{Enum: []string{"80", "http"}}}}, true},
+		{"default outside int enum", []types.Field{{Name: "port", Type: "int", Default: float64(8080), Constraints: &types.Constraints{Enum: []string{"80", "443"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFields(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateValueAgainstConstraintsRichTypes(t *testing.T) {
+	one, three := 1.0, 3.0
+	tests := []struct {
+		name     string
+		value    string
+		field    types.Field
+		expected bool
+	}{
+		{"duration valid", "1m30s", types.Field{Type: types.TypeDuration}, true},
+		{"duration invalid", "90", types.Field{Type: types.TypeDuration}, false},
+		{"duration below min seconds", "500ms", types.Field{Type: types.TypeDuration, Constraints: &types.Constraints{Min: &one}}, false},
+		{"duration within range", "2s", types.Field{Type: types.TypeDuration, Constraints: &types.Constraints{Min: &one, Max: &three}}, true},
+		{"string list valid", `["a","b"]`, types.Field{Type: types.TypeStringList}, true},
+		{"string list invalid", `[1,2]`, types.Field{Type: types.TypeStringList}, false},
+		{"string list too long", `["a","b","c","d"]`, types.Field{Type: types.TypeStringList, Constraints: &types.Constraints{Max: &three}}, false},
+		{"string list enum", `["a","b"]`, types.Field{Type: types.TypeStringList, Constraints: &types.Constraints{Enum: []string{"a", "b"}}}, true},
+		{"string list outside enum", `["a","z"]`, types.Field{Type: types.TypeStringList, Constraints: &types.Constraints{Enum: []string{"a", "b"}}}, false},
+		{"int list valid", `[1,2]`, types.Field{Type: types.TypeIntList}, true},
+		{"int list empty below min", `[]`, types.Field{Type: types.TypeIntList, Constraints: &types.Constraints{Min: &one}}, false},
+		{"string map valid", `{"a":"1"}`, types.Field{Type: types.TypeStringMap}, true},
+		{"string map invalid", `{"a":1}`, types.Field{Type: types.TypeStringMap}, false},
+		{"json valid", `{"a":[1,{"b":null}]}`, types.Field{Type: types.TypeJSON}, true},
+		{"json invalid", `{"a":`, types.Field{Type: types.TypeJSON}, false},
+		{"url valid", "https://example.com/path", types.Field{Type: types.TypeURL}, true},
+		{"url without scheme", "example.com/path", types.Field{Type: types.TypeURL}, false},
+		{"url outside enum", "https://example.org", types.Field{Type: types.TypeURL, Constraints: &types.Constraints{Enum: []string{"https://example.com"}}}, false},
+		{"secret too short", "ab", types.Field{Type: types.TypeSecret, Constraints: &types.Constraints{Min: &three}}, false},
+		{"secret valid", "s3cret", types.Field{Type: types.TypeSecret, Constraints: &types.Constraints{Min: &three}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateValueAgainstConstraints(tt.value, &tt.field); got != tt.expected {
+				t.Errorf("ValidateValueAgainstConstraints() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateValueAgainstConstraintsExtended(t *testing.T) {
+	zero, half, ten := 0.0, 0.5, 10.0
+	two, four := 2, 4
+	tests := []struct {
+		name     string
+		value    string
+		field    types.Field
+		expected bool
+	}{
+		{"float fractional min", "0.4", types.Field{Type: types.TypeFloat, Constraints: &types.Constraints{Min: &half}}, false},
+		{"float equal to min", "0.5", types.Field{Type: types.TypeFloat, Constraints: &types.Constraints{Min: &half}}, true},
+		{"float equal to exclusive min", "0.5", types.Field{Type: types.TypeFloat, Constraints: &types.Constraints{Min: &half, ExclusiveMin: true}}, false},
+		{"int equal to exclusive max", "10", types.Field{Type: types.TypeInt, Constraints: &types.Constraints{Max: &ten, ExclusiveMax: true}}, false},
+		{"int below exclusive max", "9", types.Field{Type: types.TypeInt, Constraints: &types.Constraints{Min: &zero, Max: &ten, ExclusiveMax: true}}, true},
+		{"int multiple of", "30", types.Field{Type: types.TypeInt, Constraints: &types.Constraints{MultipleOf: &ten}}, true},
+		{"int not multiple of", "35", types.Field{Type: types.TypeInt, Constraints: &types.Constraints{MultipleOf: &ten}}, false},
+		{"float multiple of", "1.5", types.Field{Type: types.TypeFloat, Constraints: &types.Constraints{MultipleOf: &half}}, true},
+		{"duration multiple of seconds", "25s", types.Field{Type: types.TypeDuration, Constraints: &types.Constraints{MultipleOf: &ten}}, false},
+		{"int enum", "443", types.Field{Type: types.TypeInt, Constraints: &types.Constraints{Enum: []string{"80", "443"}}}, true},
+		{"int outside enum", "8080", types.Field{Type: types.TypeInt, Constraints: &types.Constraints{Enum: []string{"80", "443"}}}, false},
+		{"float enum compares numerically", "0.50", types.Field{Type: types.TypeFloat, Constraints: &types.Constraints{Enum: []string{"0.5", "1"}}}, true},
+		{"int list outside enum", "[80,8080]", types.Field{Type: types.TypeIntList, Constraints: &types.Constraints{Enum: []string{"80", "443"}}}, false},
+		{"string within minLength and maxLength", "abc", types.Field{Type: types.TypeString, Constraints: &types.Constraints{MinLength: &two, MaxLength: &four}}, true},
+		{"string above maxLength", "abcde", types.Field{Type: types.TypeString, Constraints: &types.Constraints{MaxLength: &four}}, false},
+		{"maxLength counts characters", "ééé", types.Field{Type: types.TypeString, Constraints: &types.Constraints{MaxLength: &four}}, true},
+		{"list above maxLength", `[1,2,3,4,5]`, types.Field{Type: types.TypeIntList, Constraints: &types.Constraints{MaxLength: &four}}, false},
+		{"string matches pattern", "abc-123", types.Field{Type: types.TypeString, Constraints: &types.Constraints{Pattern: `^[a-z]+-\d+$`}}, true},
+		{"string does not match pattern", "ABC-123", types.Field{Type: types.TypeString, Constraints: &types.Constraints{Pattern: `^[a-z]+-\d+$`}}, false},
+		{"url matches pattern", "https://example.com", types.Field{Type: types.TypeURL, Constraints: &types.Constraints{Pattern: `^https://`}}, true},
+		{"string list element does not match pattern", `["ok","NOT"]`, types.Field{Type: types.TypeStringList, Constraints: &types.Constraints{Pattern: `^[a-z]+$`}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateValueAgainstConstraints(tt.value, &tt.field); got != tt.expected {
+				t.Errorf("ValidateValueAgainstConstraints() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	min, max := 1.0, 10.0
+	tests := []struct {
+		name  string
+		value string
+		field types.Field
+		want  *ValidationError
+	}{
+		{"valid", "5", types.Field{Name: "port", Type: types.TypeInt, Constraints: &types.Constraints{Min: &min, Max: &max}}, nil},
+		{"wrong type", "abc", types.Field{Name: "port", Type: types.TypeInt},
+			&ValidationError{Field: "port", Constraint: ConstraintType, Value: "abc", Limit: "int"}},
+		{"below min", "0", types.Field{Name: "port", Type: types.TypeInt, Constraints: &types.Constraints{Min: &min}},
+			&ValidationError{Field: "port", Constraint: ConstraintMin, Value: "0", Limit: "1"}},
+		{"at exclusive max", "10", types.Field{Name: "port", Type: types.TypeInt, Constraints: &types.Constraints{Max: &max, ExclusiveMax: true}},
+			&ValidationError{Field: "port", Constraint: ConstraintExclusiveMax, Value: "10", Limit: "10"}},
+		{"outside enum", "debug", types.Field{Name: "logLevel", Type: types.TypeString, Constraints: &types.Constraints{Enum: []string{"info", "warn"}}},
+			&ValidationError{Field: "logLevel", Constraint: ConstraintEnum, Value: "debug", Limit: "info, warn"}},
+		{"secret value is left out", "ab", types.Field{Name: "password", Type: types.TypeSecret, Constraints: &types.Constraints{Min: &max}},
+			&ValidationError{Field: "password", Constraint: ConstraintMin, Value: "", Limit: "10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateValue(tt.value, &tt.field)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidateValue() = %v, want nil", err)
+				}
+				return
+			}
+			var got *ValidationError
+			if !errors.As(err, &got) {
+				t.Fatalf("ValidateValue() = %v, want a *ValidationError", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("ValidateValue() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package rigel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// SchemaDiff describes how one schema differs from another.
+type SchemaDiff struct {
+	DescriptionChanged bool
+	OldDescription     string
+	NewDescription     string
+	Added              []types.Field // Added are the fields only the new schema has, sorted by name
+	Removed            []types.Field // Removed are the fields only the old schema has, sorted by name
+	Changed            []FieldChange // Changed are the fields both have but that differ, sorted by name
+}
+
+// FieldChange describes how a field differs between two schemas.
+type FieldChange struct {
+	Name    string
+	Old     types.Field
+	New     types.Field
+	Changes []string // Changes lists what differs, such as "type: int -> string"
+}
+
+// DiffSchemas returns the differences between the schemas from and to. Fields are matched by name,
+// so their order does not matter. Versions are not compared.
+func DiffSchemas(from, to types.Schema) SchemaDiff {
+	var diff SchemaDiff
+	if from.Description != to.Description {
+		diff.DescriptionChanged = true
+		diff.OldDescription = from.Description
+		diff.NewDescription = to.Description
+	}
+
+	for _, field := range to.Fields {
+		old := findField(from.Fields, field.Name)
+		if old == nil {
+			diff.Added = append(diff.Added, field)
+			continue
+		}
+		if changes := fieldChanges(*old, field); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, FieldChange{Name: field.Name, Old: *old, New: field, Changes: changes})
+		}
+	}
+	for _, field := range from.Fields {
+		if findField(to.Fields, field.Name) == nil {
+			diff.Removed = append(diff.Removed, field)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Name < diff.Added[j].Name })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Name < diff.Removed[j].Name })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+	return diff
+}
+
+// Empty reports whether the schemas are the same.
+func (d SchemaDiff) Empty() bool {
+	return !d.DescriptionChanged && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns the differences one per line: "+" for added fields, "-" for removed fields and
+// "~" for changed fields and the description.
+func (d SchemaDiff) String() string {
+	var lines []string
+	if d.DescriptionChanged {
+		lines = append(lines, fmt.Sprintf("~ description: %q -> %q", d.OldDescription, d.NewDescription))
+	}
+	for _, field := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s (%s)", field.Name, field.Type))
+	}
+	for _, field := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s (%s)", field.Name, field.Type))
+	}
+	for _, change := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s: %s", change.Name, strings.Join(change.Changes, "; ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fieldChanges lists how the field differs between old and new.
+func fieldChanges(old, new types.Field) []string {
+	var changes []string
+	if old.Type != new.Type {
+		changes = append(changes, fmt.Sprintf("type: %s -> %s", old.Type, new.Type))
+	}
+	if old.Description != new.Description {
+		changes = append(changes, fmt.Sprintf("description: %q -> %q", old.Description, new.Description))
+	}
+	if old.Required != new.Required {
+		changes = append(changes, fmt.Sprintf("required: %t -> %t", old.Required, new.Required))
+	}
+	// Defaults and constraints are compared as JSON, so that 8080 and 8080.0 are the same default,
+	// and enum values as a set, so that reordering them is no change
+	if o, n := diffJSON(old.Default), diffJSON(new.Default); o != n {
+		changes = append(changes, fmt.Sprintf("default: %s -> %s", o, n))
+	}
+	if constraintsKey(old.Constraints) != constraintsKey(new.Constraints) {
+		changes = append(changes, fmt.Sprintf("constraints: %s -> %s", constraintsJSON(old.Constraints), constraintsJSON(new.Constraints)))
+	}
+	return changes
+}
+
+// diffJSON returns v as JSON for comparing and showing it, or "none" if it is nil.
+func diffJSON(v any) string {
+	if v == nil {
+		return "none"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// constraintsJSON is like diffJSON, with no constraints being the same as empty constraints.
+func constraintsJSON(c *types.Constraints) string {
+	if c == nil {
+		c = &types.Constraints{}
+	}
+	return diffJSON(c)
+}
+
+// constraintsKey is like constraintsJSON with the enum values sorted and without duplicates, for comparing constraints.
+func constraintsKey(c *types.Constraints) string {
+	if c == nil || len(c.Enum) == 0 {
+		return constraintsJSON(c)
+	}
+	set := *c
+	set.Enum = enumSet(c.Enum)
+	return diffJSON(&set)
+}
+
+// enumSet returns the enum values sorted and without duplicates.
+func enumSet(enum []string) []string {
+	sorted := append([]string(nil), enum...)
+	sort.Strings(sorted)
+	set := sorted[:0]
+	for i, value := range sorted {
+		if i == 0 || value != sorted[i-1] {
+			set = append(set, value)
+		}
+	}
+	return set
+}
+
+// Kinds of SchemaChange.
+const (
+	ChangeAddedField   = "added_field"
+	ChangeRemovedField = "removed_field"
+	ChangeType         = "type_change"
+	ChangeTightened    = "tightened_constraint" // ChangeTightened is a constraint that accepts fewer values
+	ChangeLoosened     = "loosened_constraint"  // ChangeLoosened is a constraint that accepts more values
+	ChangeDefault      = "default_change"
+	ChangeDescription  = "description_change"
+)
+
+// SchemaChange is a single change between two schemas. A breaking change is one that a named config
+// valid with the old schema may not be valid with under the new one.
+type SchemaChange struct {
+	Field    string `json:"field"` // Field is empty for a change of the schema description
+	Kind     string `json:"kind"`
+	Detail   string `json:"detail"`
+	Breaking bool   `json:"breaking"`
+}
+
+// String returns the change in the form "breaking type_change port: int -> string".
+func (c SchemaChange) String() string {
+	compatibility := "compatible"
+	if c.Breaking {
+		compatibility = "breaking"
+	}
+	if c.Field == "" {
+		return fmt.Sprintf("%s %s %s", compatibility, c.Kind, c.Detail)
+	}
+	return fmt.Sprintf("%s %s %s: %s", compatibility, c.Kind, c.Field, c.Detail)
+}
+
+// Changes classifies the differences as added and removed fields, type changes and tightened and
+// loosened constraints, and marks each as backward-compatible or breaking. Fields that are no longer
+// in the schema and new required fields without a default are breaking, as are type changes and
+// tightened constraints; a changed pattern counts as tightened, since it may reject old values.
+func (d SchemaDiff) Changes() []SchemaChange {
+	var changes []SchemaChange
+	if d.DescriptionChanged {
+		changes = append(changes, SchemaChange{Kind: ChangeDescription, Detail: fmt.Sprintf("%q -> %q", d.OldDescription, d.NewDescription)})
+	}
+	for _, field := range d.Added {
+		detail := field.Type
+		breaking := field.Required && field.Default == nil
+		if breaking {
+			detail += ", required without a default"
+		}
+		changes = append(changes, SchemaChange{Field: field.Name, Kind: ChangeAddedField, Detail: detail, Breaking: breaking})
+	}
+	for _, field := range d.Removed {
+		changes = append(changes, SchemaChange{Field: field.Name, Kind: ChangeRemovedField, Detail: field.Type, Breaking: true})
+	}
+	for _, change := range d.Changed {
+		changes = append(changes, classifyFieldChange(change.Old, change.New)...)
+	}
+	return changes
+}
+
+// classifyFieldChange returns the changes of a field that both schemas have.
+func classifyFieldChange(old, new types.Field) []SchemaChange {
+	var changes []SchemaChange
+	add := func(kind, detail string) {
+		breaking := kind == ChangeType || kind == ChangeTightened
+		changes = append(changes, SchemaChange{Field: new.Name, Kind: kind, Detail: detail, Breaking: breaking})
+	}
+
+	if old.Type != new.Type {
+		add(ChangeType, fmt.Sprintf("%s -> %s", old.Type, new.Type))
+	}
+	if old.Description != new.Description {
+		add(ChangeDescription, fmt.Sprintf("%q -> %q", old.Description, new.Description))
+	}
+
+	// A field becoming required, or a required field losing its default, is only breaking for
+	// configs that relied on the field being optional or on its default
+	switch {
+	case !old.Required && new.Required && new.Default == nil:
+		add(ChangeTightened, "required: false -> true")
+	case !old.Required && new.Required:
+		changes = append(changes, SchemaChange{Field: new.Name, Kind: ChangeTightened, Detail: "required: false -> true, with a default"})
+	case old.Required && !new.Required:
+		add(ChangeLoosened, "required: true -> false")
+	}
+	if o, n := diffJSON(old.Default), diffJSON(new.Default); o != n {
+		if new.Required && new.Default == nil {
+			add(ChangeTightened, fmt.Sprintf("default of required field: %s -> %s", o, n))
+		} else {
+			add(ChangeDefault, fmt.Sprintf("%s -> %s", o, n))
+		}
+	}
+
+	oc, nc := old.Constraints, new.Constraints
+	if oc == nil {
+		oc = &types.Constraints{}
+	}
+	if nc == nil {
+		nc = &types.Constraints{}
+	}
+	for _, c := range constraintChanges(oc, nc) {
+		add(c.kind, c.detail)
+	}
+	return changes
+}
+
+// constraintChange is a change of a single constraint.
+type constraintChange struct {
+	kind   string
+	detail string
+}
+
+// constraintChanges compares each constraint in turn and says whether it now accepts fewer values or more.
+func constraintChanges(old, new *types.Constraints) []constraintChange {
+	var changes []constraintChange
+	add := func(tightened bool, format string, args ...any) {
+		kind := ChangeLoosened
+		if tightened {
+			kind = ChangeTightened
+		}
+		changes = append(changes, constraintChange{kind: kind, detail: fmt.Sprintf(format, args...)})
+	}
+
+	if tightened, changed := compareBound(old.Min, new.Min, false); changed {
+		add(tightened, "min: %s -> %s", floatString(old.Min), floatString(new.Min))
+	}
+	if tightened, changed := compareBound(old.Max, new.Max, true); changed {
+		add(tightened, "max: %s -> %s", floatString(old.Max), floatString(new.Max))
+	}
+	if old.ExclusiveMin != new.ExclusiveMin && new.Min != nil {
+		add(new.ExclusiveMin, "exclusiveMin: %t -> %t", old.ExclusiveMin, new.ExclusiveMin)
+	}
+	if old.ExclusiveMax != new.ExclusiveMax && new.Max != nil {
+		add(new.ExclusiveMax, "exclusiveMax: %t -> %t", old.ExclusiveMax, new.ExclusiveMax)
+	}
+	if tightened, changed := compareBound(intFloat(old.MinLength), intFloat(new.MinLength), false); changed {
+		add(tightened, "minLength: %s -> %s", intString(old.MinLength), intString(new.MinLength))
+	}
+	if tightened, changed := compareBound(intFloat(old.MaxLength), intFloat(new.MaxLength), true); changed {
+		add(tightened, "maxLength: %s -> %s", intString(old.MaxLength), intString(new.MaxLength))
+	}
+
+	if old.Pattern != new.Pattern {
+		// Whether one regular expression accepts more than another cannot be told in general,
+		// so only dropping the pattern loosens it
+		add(new.Pattern != "", "pattern: %q -> %q", old.Pattern, new.Pattern)
+	}
+
+	if floatString(old.MultipleOf) != floatString(new.MultipleOf) {
+		// A new step that is a multiple of the old one accepts fewer values, and one the old step is a multiple of, more
+		tightened := new.MultipleOf != nil
+		if old.MultipleOf != nil && new.MultipleOf != nil && isMultiple(*old.MultipleOf, *new.MultipleOf) {
+			tightened = false
+		}
+		add(tightened, "multipleOf: %s -> %s", floatString(old.MultipleOf), floatString(new.MultipleOf))
+	}
+
+	// Enum values are a set, so only values added or removed change it
+	if strings.Join(enumSet(old.Enum), "\x00") != strings.Join(enumSet(new.Enum), "\x00") {
+		tightened := len(new.Enum) > 0 && (len(old.Enum) == 0 || !isSubset(old.Enum, new.Enum))
+		add(tightened, "enum: %s -> %s", enumString(old.Enum), enumString(new.Enum))
+	}
+	return changes
+}
+
+// compareBound compares an old and a new lower bound, or upper bound if upper is set, where nil is no bound.
+func compareBound(old, new *float64, upper bool) (tightened, changed bool) {
+	switch {
+	case old == nil && new == nil:
+		return false, false
+	case old == nil:
+		return true, true
+	case new == nil:
+		return false, true
+	case *old == *new:
+		return false, false
+	case upper:
+		return *new < *old, true
+	default:
+		return *new > *old, true
+	}
+}
+
+// isMultiple reports whether x is a whole multiple of step.
+func isMultiple(x, step float64) bool {
+	if step == 0 {
+		return false
+	}
+	q := x / step
+	return math.Abs(q-math.Round(q)) < 1e-9
+}
+
+// isSubset reports whether every element of a is in b.
+func isSubset(a, b []string) bool {
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func intFloat(i *int) *float64 {
+	if i == nil {
+		return nil
+	}
+	f := float64(*i)
+	return &f
+}
+
+func floatString(f *float64) string {
+	if f == nil {
+		return "none"
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+func intString(i *int) string {
+	if i == nil {
+		return "none"
+	}
+	return strconv.Itoa(*i)
+}
+
+func enumString(enum []string) string {
+	if len(enum) == 0 {
+		return "none"
+	}
+	return "[" + strings.Join(enum, ", ") + "]"
+}
+
+// SchemaCompatibility is the result of comparing two versions of a schema.
+type SchemaCompatibility struct {
+	From    int            `json:"from"`
+	To      int            `json:"to"`
+	Changes []SchemaChange `json:"changes"`
+	// InvalidConfigs lists the problems of each named config under the From version that
+	// would fail validation under the To version.
+	InvalidConfigs map[string][]string `json:"invalid_configs"`
+}
+
+// Breaking reports whether any change is breaking.
+func (c *SchemaCompatibility) Breaking() bool {
+	for _, change := range c.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareSchemaVersions compares the from and to versions of the client's schema, and validates every
+// named config under the from version against the to version.
+func (r *Rigel) CompareSchemaVersions(ctx context.Context, from, to int) (*SchemaCompatibility, error) {
+	fromSchema, err := r.getSchemaVersion(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version %d: %w", from, err)
+	}
+	toSchema, err := r.getSchemaVersion(ctx, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version %d: %w", to, err)
+	}
+
+	problems, err := r.configProblems(ctx, from, *toSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaCompatibility{
+		From:           from,
+		To:             to,
+		Changes:        DiffSchemas(*fromSchema, *toSchema).Changes(),
+		InvalidConfigs: problems,
+	}, nil
+}
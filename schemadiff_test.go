@@ -0,0 +1,150 @@
+package rigel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/remiges-tech/rigel/memstore"
+	"github.com/remiges-tech/rigel/types"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	max := 100.0
+	from := types.Schema{
+		Description: "old",
+		Fields: []types.Field{
+			{Name: "host", Type: types.TypeString},
+			{Name: "port", Type: types.TypeInt, Default: float64(8080)},
+			{Name: "debug", Type: types.TypeBool},
+		},
+	}
+	to := types.Schema{
+		Description: "new",
+		Fields: []types.Field{
+			{Name: "port", Type: types.TypeInt, Default: 8081, Required: true, Constraints: &types.Constraints{Max: &max}},
+			{Name: "host", Type: types.TypeString, Constraints: &types.Constraints{}},
+			{Name: "timeout", Type: types.TypeDuration},
+		},
+	}
+
+	diff := DiffSchemas(from, to)
+	want := `~ description: "old" -> "new"
++ timeout (duration)
+- debug (bool)
+~ port: required: false -> true; default: 8080 -> 8081; constraints: {} -> {"max":100}`
+	if got := diff.String(); got != want {
+		t.Errorf("Expected diff\n%s\ngot\n%s", want, got)
+	}
+
+	if !DiffSchemas(from, from).Empty() {
+		t.Errorf("Expected no differences between a schema and itself")
+	}
+}
+
+func TestSchemaDiffChanges(t *testing.T) {
+	min1, min5, max100, max200, step5, step10 := 1.0, 5.0, 100.0, 200.0, 5.0, 10.0
+	from := types.Schema{Fields: []types.Field{
+		{Name: "port", Type: types.TypeInt, Constraints: &types.Constraints{Min: &min1, Max: &max100}},
+		{Name: "workers", Type: types.TypeInt, Constraints: &types.Constraints{MultipleOf: &step10}},
+		{Name: "level", Type: types.TypeString, Constraints: &types.Constraints{Enum: []string{"info", "debug"}}},
+		{Name: "debug", Type: types.TypeBool},
+		{Name: "timeout", Type: types.TypeInt},
+	}}
+	to := types.Schema{Fields: []types.Field{
+		{Name: "port", Type: types.TypeInt, Constraints: &types.Constraints{Min: &min5, Max: &max200}},
+		{Name: "workers", Type: types.TypeInt, Constraints: &types.Constraints{MultipleOf: &step5}},
+		{Name: "level", Type: types.TypeString, Constraints: &types.Constraints{Enum: []string{"info", "debug", "warn"}}},
+		{Name: "timeout", Type: types.TypeDuration},
+		{Name: "region", Type: types.TypeString, Required: true},
+		{Name: "zone", Type: types.TypeString, Required: true, Default: "a"},
+	}}
+
+	want := []SchemaChange{
+		{Field: "region", Kind: ChangeAddedField, Detail: "string, required without a default", Breaking: true},
+		{Field: "zone", Kind: ChangeAddedField, Detail: "string"},
+		{Field: "debug", Kind: ChangeRemovedField, Detail: "bool", Breaking: true},
+		{Field: "level", Kind: ChangeLoosened, Detail: "enum: [info, debug] -> [info, debug, warn]"},
+		{Field: "port", Kind: ChangeTightened, Detail: "min: 1 -> 5", Breaking: true},
+		{Field: "port", Kind: ChangeLoosened, Detail: "max: 100 -> 200"},
+		{Field: "timeout", Kind: ChangeType, Detail: "int -> duration", Breaking: true},
+		{Field: "workers", Kind: ChangeLoosened, Detail: "multipleOf: 10 -> 5"},
+	}
+	got := DiffSchemas(from, to).Changes()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d changes, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Change %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestCompareSchemaVersions(t *testing.T) {
+	ctx := context.Background()
+	rigelClient := New(memstore.New(), "app", "module", 1, "prod")
+	v1 := types.Schema{Version: 1, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString},
+		{Name: "port", Type: types.TypeInt},
+	}}
+	v2 := types.Schema{Version: 2, Fields: []types.Field{
+		{Name: "host", Type: types.TypeString},
+		{Name: "port", Type: types.TypeString, Constraints: &types.Constraints{Pattern: "^[0-9]+$"}},
+	}}
+	for _, schema := range []types.Schema{v1, v2} {
+		if err := rigelClient.AddSchema(ctx, schema); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := rigelClient.Set(ctx, "port", "8080"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.WithConfig("dev").Set(ctx, "port", "80"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rigelClient.WithVersion(2).WithConfig("dev").Set(ctx, "port", "http"); err == nil {
+		t.Fatalf("Expected a value not matching the pattern to be rejected")
+	}
+
+	compatibility, err := rigelClient.WithVersion(1).CompareSchemaVersions(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !compatibility.Breaking() || len(compatibility.Changes) != 2 {
+		t.Errorf("Expected a breaking type change and pattern, got %v", compatibility.Changes)
+	}
+	if len(compatibility.InvalidConfigs) != 0 {
+		t.Errorf("Expected every config to be valid under version 2, got %v", compatibility.InvalidConfigs)
+	}
+
+	// A config with a value the new version rejects is listed
+	if err := rigelClient.WithConfig("prod").Set(ctx, "host", "example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	v3 := types.Schema{Version: 3, Fields: []types.Field{{Name: "port", Type: types.TypeInt}}}
+	if err := rigelClient.AddSchema(ctx, v3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	compatibility, err = rigelClient.CompareSchemaVersions(ctx, 1, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if problems := compatibility.InvalidConfigs["prod"]; len(problems) != 1 || len(compatibility.InvalidConfigs) != 1 {
+		t.Errorf("Expected prod to be invalid under version 3, got %v", compatibility.InvalidConfigs)
+	}
+}
+
+func TestDiffSchemasEnumReorder(t *testing.T) {
+	from := types.Schema{Fields: []types.Field{
+		{Name: "level", Type: types.TypeString, Constraints: &types.Constraints{Enum: []string{"info", "debug", "warn"}}},
+	}}
+	to := types.Schema{Fields: []types.Field{
+		{Name: "level", Type: types.TypeString, Constraints: &types.Constraints{Enum: []string{"warn", "info", "debug"}}},
+	}}
+
+	// Enum values are a set, so reordering them changes nothing
+	diff := DiffSchemas(from, to)
+	if !diff.Empty() || len(diff.Changes()) != 0 {
+		t.Errorf("Expected no changes, got\n%s", diff)
+	}
+}
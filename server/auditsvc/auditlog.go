@@ -0,0 +1,79 @@
+package auditsvc
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// AuditLogReqParams are the query parameters of /auditlog. Every parameter is optional and narrows
+// down the records returned; since and until are RFC 3339 times.
+type AuditLogReqParams struct {
+	App       string    `form:"app"`
+	Module    string    `form:"module"`
+	Version   int       `form:"ver"`
+	Config    string    `form:"config"`
+	Key       string    `form:"key"`
+	Actor     string    `form:"actor"`
+	Source    string    `form:"source"`
+	Operation string    `form:"op"`
+	Outcome   string    `form:"outcome"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit"`
+}
+
+// AuditLog: handles the GET /auditlog request
+func AuditLog(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of AuditLog()")
+
+	var queryParams AuditLogReqParams
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		l.Error(err).Log("error unmarshalling query paramaeters to struct")
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(wscutils.ERRCODE_INVALID_REQUEST))
+		return
+	}
+
+	// Records are redacted like /confighistory, so reading them needs the read permission on what they are about
+	if !auth.Authorize(c, s, auth.PermRead, queryParams.App, queryParams.Module, queryParams.Config) {
+		return
+	}
+
+	// Extracting Rigel client from service dependency
+	rigelClient := s.Dependencies["rigel"]
+	r, ok := rigelClient.(*rigel.Rigel)
+	if !ok {
+		str := "rigelClient"
+		l.Debug0().LogDebug("Invalid Rigel Client Dependency:", rigelClient)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &str)}))
+		return
+	}
+
+	records, err := r.AuditLog(c, rigel.AuditFilter{
+		App:       queryParams.App,
+		Module:    queryParams.Module,
+		Version:   queryParams.Version,
+		Config:    queryParams.Config,
+		Key:       queryParams.Key,
+		Actor:     queryParams.Actor,
+		Source:    queryParams.Source,
+		Operation: queryParams.Operation,
+		Outcome:   queryParams.Outcome,
+		Since:     queryParams.Since,
+		Until:     queryParams.Until,
+		Limit:     queryParams.Limit,
+	})
+	if err != nil {
+		l.LogActivity("error while reading audit log:", err.Error())
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(utils.ErrcodeAuditLogUnavailable))
+		return
+	}
+
+	wscutils.SendSuccessResponse(c, wscutils.NewSuccessResponse(map[string]any{"records": records}))
+}
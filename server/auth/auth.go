@@ -0,0 +1,121 @@
+// Package auth authenticates the callers of the Rigel web services and checks what they may do.
+//
+// Callers send a bearer token, either a static token or a JWT, in the Authorization header.
+// Middleware verifies it and records who the caller is; handlers then call Authorize with the
+// permission they need on the app, module and named config of the request.
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// identityKey is the key of the caller's Identity in the gin context
+const identityKey = "rigel_identity"
+
+// ErrInvalidToken is returned by an Authenticator that does not accept a token.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity is an authenticated caller.
+type Identity struct {
+	Subject string   // Subject names the caller, and is recorded as the actor of its changes
+	Roles   []string // Roles are looked up in the Policy to find the caller's permissions
+}
+
+// Authenticator verifies a bearer token and returns the identity it belongs to.
+type Authenticator interface {
+	Authenticate(token string) (*Identity, error)
+}
+
+// StaticToken is a long-lived token given to a caller, such as a deployment pipeline.
+type StaticToken struct {
+	Token   string   `json:"token"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// StaticTokens accepts a fixed set of tokens.
+type StaticTokens []StaticToken
+
+// Authenticate returns the identity of token. Tokens are compared in constant time.
+func (t StaticTokens) Authenticate(token string) (*Identity, error) {
+	var found *StaticToken
+	for i := range t {
+		if subtle.ConstantTimeCompare([]byte(t[i].Token), []byte(token)) == 1 {
+			found = &t[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalidToken
+	}
+	return &Identity{Subject: found.Subject, Roles: found.Roles}, nil
+}
+
+// Authenticators accepts a token if any of its authenticators does.
+type Authenticators []Authenticator
+
+// Authenticate returns the identity given by the first authenticator that accepts token.
+func (a Authenticators) Authenticate(token string) (*Identity, error) {
+	errs := []error{ErrInvalidToken}
+	for _, authenticator := range a {
+		identity, err := authenticator.Authenticate(token)
+		if err == nil {
+			return identity, nil
+		}
+		if !errors.Is(err, ErrInvalidToken) {
+			errs = append(errs, err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Middleware returns a gin middleware that rejects requests without a bearer token accepted by
+// authenticator, and records the identity of the others for Authorize and the audit log.
+func Middleware(authenticator Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := extractToken(c.Request.Header.Get("Authorization"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, wscutils.NewErrorResponse(wscutils.ErrcodeTokenMissing))
+			return
+		}
+
+		identity, err := authenticator.Authenticate(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, wscutils.NewErrorResponse(wscutils.ErrcodeTokenVerificationFailed))
+			return
+		}
+
+		c.Set(identityKey, identity)
+		c.Set(utils.ActorKey, identity.Subject)
+		c.Next()
+	}
+}
+
+// IdentityFrom returns the identity recorded by Middleware, if any.
+func IdentityFrom(c *gin.Context) (*Identity, bool) {
+	value, ok := c.Get(identityKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := value.(*Identity)
+	return identity, ok
+}
+
+// extractToken returns the token of an Authorization header of the form "Bearer <token>".
+func extractToken(header string) (string, error) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return "", fmt.Errorf("missing or incorrect Authorization header format")
+	}
+	if token == "" {
+		return "", fmt.Errorf("missing token in Authorization header")
+	}
+	return token, nil
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestStaticTokens(t *testing.T) {
+	tokens := StaticTokens{{Token: "s3cret", Subject: "deployer", Roles: []string{"prod-writer"}}}
+
+	identity, err := tokens.Authenticate("s3cret")
+	if err != nil || identity.Subject != "deployer" || len(identity.Roles) != 1 {
+		t.Errorf("Expected the deployer identity, got %+v (err %v)", identity, err)
+	}
+	if _, err := tokens.Authenticate("wrong"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	secret := []byte("secret")
+	authenticator, err := NewJWTAuthenticator("HS256", secret)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	authenticator.Issuer = "idp"
+
+	sign := func(method jwt.SigningMethod, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(secret)
+		if err != nil {
+			t.Fatalf("Expected no error signing token, got %v", err)
+		}
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	identity, err := authenticator.Authenticate(sign(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": "idp", "exp": exp, "roles": []string{"reader", "writer"}}))
+	if err != nil || identity.Subject != "alice" || len(identity.Roles) != 2 {
+		t.Errorf("Expected alice with 2 roles, got %+v (err %v)", identity, err)
+	}
+
+	invalid := map[string]string{
+		"expired":      sign(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": "idp", "exp": time.Now().Add(-time.Hour).Unix()}),
+		"wrong issuer": sign(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": "other", "exp": exp}),
+		"no subject":   sign(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "idp", "exp": exp}),
+		"no expiry":    sign(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": "idp"}),
+		"wrong alg":    sign(jwt.SigningMethodHS512, jwt.MapClaims{"sub": "alice", "iss": "idp", "exp": exp}),
+		"garbage":      "not.a.token",
+	}
+	for name, token := range invalid {
+		if _, err := authenticator.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	// Tokens that live longer than allowed are refused
+	authenticator.MaxLifetime = 30 * time.Minute
+	if _, err := authenticator.Authenticate(sign(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": "idp", "exp": exp})); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a token expiring in an hour to be refused, got %v", err)
+	}
+
+	if _, err := NewJWTAuthenticator("none", nil); err == nil {
+		t.Errorf("Expected the none algorithm to be rejected")
+	}
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	policy := &Policy{
+		Roles: map[string][]Grant{
+			"prod-writer":  {{App: "banking", Module: "*", Config: "prod-*", Permissions: []string{PermWrite}}},
+			"schema-admin": {{App: "banking", Permissions: []string{PermSchemaAdmin}}},
+			"auditor":      {{Permissions: []string{PermRead}}},
+		},
+		Subjects: map[string][]string{"carol": {"schema-admin"}},
+	}
+	writer := &Identity{Subject: "bob", Roles: []string{"prod-writer"}}
+	admin := &Identity{Subject: "carol"}
+	auditor := &Identity{Subject: "dave", Roles: []string{"auditor"}}
+
+	tests := []struct {
+		name     string
+		identity *Identity
+		perm     string
+		app      string
+		config   string
+		want     bool
+	}{
+		{"write matching config", writer, PermWrite, "banking", "prod-us", true},
+		{"write implies read", writer, PermRead, "banking", "prod-us", true},
+		{"config outside grant", writer, PermWrite, "banking", "dev", false},
+		{"app outside grant", writer, PermWrite, "lending", "prod-us", false},
+		{"read schema of grant", writer, PermRead, "banking", "", true},
+		{"no schema admin", writer, PermSchemaAdmin, "banking", "", false},
+		{"schema admin by subject", admin, PermSchemaAdmin, "banking", "", true},
+		{"schema admin cannot write", admin, PermWrite, "banking", "prod-us", false},
+		{"read everywhere", auditor, PermRead, "", "", true},
+		{"read only", auditor, PermWrite, "banking", "prod-us", false},
+		{"anonymous", nil, PermRead, "banking", "prod-us", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Allowed(tt.identity, tt.perm, tt.app, "transactions", tt.config); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(StaticTokens{{Token: "s3cret", Subject: "deployer"}}))
+	router.GET("/", func(c *gin.Context) {
+		identity, _ := IdentityFrom(c)
+		c.String(http.StatusOK, identity.Subject)
+	})
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{"Bearer s3cret", http.StatusOK},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("Authorization %q: expected status %d, got %d", tt.header, tt.status, w.Code)
+		}
+		if tt.status == http.StatusOK && w.Body.String() != "deployer" {
+			t.Errorf("Expected the identity to be recorded, got %q", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// Config is the auth configuration of the server, read from a JSON file by LoadConfig.
+type Config struct {
+	Tokens StaticTokens `json:"tokens"`
+	JWT    *JWTConfig   `json:"jwt"`
+	Policy
+}
+
+// JWTConfig configures a JWTAuthenticator. The key is given either inline or as a file.
+type JWTConfig struct {
+	Alg        string `json:"alg"`
+	Key        string `json:"key"`
+	KeyFile    string `json:"key_file"`
+	Issuer     string `json:"issuer"`
+	Audience   string `json:"audience"`
+	RolesClaim string `json:"roles_claim"`
+
+	// MaxLifetime, such as "24h", caps how far in the future the expiry of a token may be
+	MaxLifetime string `json:"max_lifetime"`
+}
+
+// LoadConfig reads the auth configuration at file and returns the authenticator and policy it describes.
+func LoadConfig(file string) (Authenticator, *Policy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read auth config: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse auth config: %w", err)
+	}
+
+	var authenticators Authenticators
+	for _, token := range config.Tokens {
+		if token.Token == "" || token.Subject == "" {
+			return nil, nil, fmt.Errorf("every static token needs a token and a subject")
+		}
+	}
+	if len(config.Tokens) > 0 {
+		authenticators = append(authenticators, config.Tokens)
+	}
+
+	if config.JWT != nil {
+		key := []byte(config.JWT.Key)
+		if config.JWT.KeyFile != "" {
+			if key, err = os.ReadFile(config.JWT.KeyFile); err != nil {
+				return nil, nil, fmt.Errorf("failed to read JWT key: %w", err)
+			}
+		}
+		jwtAuthenticator, err := NewJWTAuthenticator(config.JWT.Alg, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		jwtAuthenticator.Issuer = config.JWT.Issuer
+		jwtAuthenticator.Audience = config.JWT.Audience
+		if config.JWT.RolesClaim != "" {
+			jwtAuthenticator.RolesClaim = config.JWT.RolesClaim
+		}
+		if config.JWT.MaxLifetime != "" {
+			if jwtAuthenticator.MaxLifetime, err = time.ParseDuration(config.JWT.MaxLifetime); err != nil {
+				return nil, nil, fmt.Errorf("invalid JWT max_lifetime: %w", err)
+			}
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, nil, fmt.Errorf("auth config has neither tokens nor a JWT key")
+	}
+	for role, grants := range config.Roles {
+		for _, grant := range grants {
+			for _, pattern := range []string{grant.App, grant.Module, grant.Config} {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, nil, fmt.Errorf("role %s has invalid pattern %q", role, pattern)
+				}
+			}
+			for _, perm := range grant.Permissions {
+				if perm != PermRead && perm != PermWrite && perm != PermSchemaAdmin {
+					return nil, nil, fmt.Errorf("role %s has unknown permission %q", role, perm)
+				}
+			}
+		}
+	}
+	return authenticators, &config.Policy, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// defaultRolesClaim is the claim JWTAuthenticator reads roles from unless told otherwise
+const defaultRolesClaim = "roles"
+
+// JWTAuthenticator accepts JWTs signed with a locally configured key. The subject of the identity
+// is the "sub" claim, and its roles are read from RolesClaim.
+type JWTAuthenticator struct {
+	Method     jwt.SigningMethod
+	Key        any    // Key is the HMAC secret, or the public key for RSA, ECDSA and EdDSA
+	Issuer     string // Issuer, if set, must match the "iss" claim
+	Audience   string // Audience, if set, must be in the "aud" claim
+	RolesClaim string // RolesClaim defaults to "roles"
+
+	// MaxLifetime, if set, is how far in the future the "exp" claim may be, so that long-lived
+	// tokens are refused.
+	MaxLifetime time.Duration
+}
+
+// NewJWTAuthenticator returns a JWTAuthenticator for tokens signed with the algorithm alg, such as
+// HS256, RS256, ES256 or EdDSA. For HMAC algorithms key is the shared secret; for the others it is
+// the PEM encoded public key.
+func NewJWTAuthenticator(alg string, key []byte) (*JWTAuthenticator, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+
+	var verifyKey any
+	var err error
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(key) == 0 {
+			return nil, fmt.Errorf("missing JWT secret")
+		}
+		verifyKey = key
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(key)
+	case *jwt.SigningMethodECDSA:
+		verifyKey, err = jwt.ParseECPublicKeyFromPEM(key)
+	case *jwt.SigningMethodEd25519:
+		verifyKey, err = jwt.ParseEdPublicKeyFromPEM(key)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key: %w", err)
+	}
+
+	return &JWTAuthenticator{Method: method, Key: verifyKey, RolesClaim: defaultRolesClaim}, nil
+}
+
+// Authenticate verifies the signature and the time, issuer and audience claims of token, and
+// returns the identity it names. Tokens must expire: a token without an "exp" claim is refused.
+func (a *JWTAuthenticator) Authenticate(token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		// Only the configured algorithm is accepted, so an HMAC token cannot be checked with a public key
+		if t.Method.Alg() != a.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return a.Key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// The parser only checks "exp" if it is there
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Unix(), true) {
+		return nil, fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	}
+	if a.MaxLifetime > 0 && claims.VerifyExpiresAt(now.Add(a.MaxLifetime).Unix(), false) {
+		return nil, fmt.Errorf("%w: expiry is more than %s away", ErrInvalidToken, a.MaxLifetime)
+	}
+
+	if a.Issuer != "" && !claims.VerifyIssuer(a.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if a.Audience != "" && !claims.VerifyAudience(a.Audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	rolesClaim := a.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = defaultRolesClaim
+	}
+	return &Identity{Subject: subject, Roles: stringsClaim(claims[rolesClaim])}, nil
+}
+
+// stringsClaim returns the strings of a claim that is either a list of strings or a single
+// space-separated string, as OAuth scopes are.
+func stringsClaim(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// PolicyDependency is the name of the service dependency that holds the *Policy handlers enforce.
+// If the service has no policy, authorization is disabled.
+const PolicyDependency = "authPolicy"
+
+// Permissions that can be granted
+const (
+	PermRead        = "read"         // PermRead allows reading schemas, config values and their history
+	PermWrite       = "write"        // PermWrite allows changing config values, and implies PermRead
+	PermSchemaAdmin = "schema-admin" // PermSchemaAdmin allows adding and deleting schemas, and implies PermRead
+)
+
+// Grant gives permissions on the schemas and named configs matching App, Module and Config.
+// Each of them is a path.Match pattern such as "prod-*"; empty or "*" matches anything.
+type Grant struct {
+	App         string   `json:"app"`
+	Module      string   `json:"module"`
+	Config      string   `json:"config"`
+	Permissions []string `json:"permissions"`
+}
+
+// Policy maps roles to the grants they hold.
+type Policy struct {
+	Roles    map[string][]Grant  `json:"roles"`
+	Subjects map[string][]string `json:"subjects"` // Subjects gives roles to subjects, on top of those in their token
+}
+
+// Allowed reports whether identity has perm on the named config of app and module. An empty config
+// asks about the schema itself, so the Config of grants is not checked. An empty app or module
+// asks about every app or module, and is only matched by grants for any.
+func (p *Policy) Allowed(identity *Identity, perm, app, module, config string) bool {
+	if identity == nil {
+		return false
+	}
+	roles := append(append([]string{}, identity.Roles...), p.Subjects[identity.Subject]...)
+	for _, role := range roles {
+		for _, grant := range p.Roles[role] {
+			if grant.matches(app, module, config) && grant.allows(perm) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether the grant applies to the named config.
+func (g Grant) matches(app, module, config string) bool {
+	return matchPattern(g.App, app) && matchPattern(g.Module, module) && (config == "" || matchPattern(g.Config, config))
+}
+
+// allows reports whether the grant gives perm, directly or through a permission that implies it.
+func (g Grant) allows(perm string) bool {
+	for _, granted := range g.Permissions {
+		if granted == perm || (perm == PermRead && (granted == PermWrite || granted == PermSchemaAdmin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether value matches pattern, where an empty pattern matches anything.
+func matchPattern(pattern, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+// Authorize reports whether the caller of the request has perm on the named config of app and
+// module; see Policy.Allowed. If not, it sends a forbidden error response, and the handler must stop.
+// Everything is allowed when the service has no policy.
+func Authorize(c *gin.Context, s *service.Service, perm, app, module, config string) bool {
+	if Allowed(c, s, perm, app, module, config) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, wscutils.NewErrorResponse(utils.ErrcodeForbidden))
+	return false
+}
+
+// Allowed is like Authorize without sending a response, for handlers that filter what they return.
+func Allowed(c *gin.Context, s *service.Service, perm, app, module, config string) bool {
+	policy, ok := s.Dependencies[PolicyDependency].(*Policy)
+	if !ok {
+		return true
+	}
+	identity, _ := IdentityFrom(c)
+	return policy.Allowed(identity, perm, app, module, config)
+}
@@ -0,0 +1,91 @@
+package configsvc
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// configclone is the request body of /configclone. Overrides take the place of the source's values,
+// and an existing destination config is only replaced if Overwrite is set.
+type configclone struct {
+	App       string `json:"app" validate:"required"`
+	Module    string `json:"module" validate:"required"`
+	Ver       int    `json:"ver" validate:"required"`
+	Src       string `json:"src" validate:"required"`
+	Dst       string `json:"dst" validate:"required"`
+	Overrides []struct {
+		Name  string `json:"name" validate:"required"`
+		Value string `json:"value" validate:"required"`
+	} `json:"overrides"`
+	Overwrite bool `json:"overwrite"`
+}
+
+// Config_clone: handles the POST /configclone request
+func Config_clone(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_clone()")
+
+	var configclone configclone
+	err := wscutils.BindJSON(c, &configclone)
+	if err != nil {
+		l.LogActivity("error while binding json", err)
+		return
+	}
+
+	validationErrors := wscutils.WscValidate(configclone, configclone.getValsForClone)
+	if len(validationErrors) > 0 {
+		l.LogDebug("Validation errors:", validationErrors)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
+		return
+	}
+
+	// Cloning reads the source and writes the destination
+	if !auth.Authorize(c, s, auth.PermRead, configclone.App, configclone.Module, configclone.Src) ||
+		!auth.Authorize(c, s, auth.PermWrite, configclone.App, configclone.Module, configclone.Dst) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, configclone.App, configclone.Module, configclone.Ver, "")
+	if !ok {
+		return
+	}
+
+	opts := []rigel.CloneOption{}
+	if len(configclone.Overrides) > 0 {
+		overrides := make(map[string]string, len(configclone.Overrides))
+		for _, v := range configclone.Overrides {
+			overrides[v.Name] = v.Value
+		}
+		opts = append(opts, rigel.WithOverrides(overrides))
+	}
+	if configclone.Overwrite {
+		opts = append(opts, rigel.OverwriteDestination())
+	}
+
+	err = r.CloneConfig(utils.RequestContext(c), configclone.Src, configclone.Dst, opts...)
+	var exists *rigel.ConfigExistsError
+	if errors.As(err, &exists) {
+		field := "dst"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeConfigExists, &field, exists.Config)}))
+		return
+	}
+	if err != nil {
+		l.LogActivity("error while cloning config:", err)
+		sendSetError(c, err)
+		return
+	}
+	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "config cloned successfully", Messages: []wscutils.ErrorMessage{}})
+}
+
+// getValsForClone returns validation error details based on the field and tag.
+func (config *configclone) getValsForClone(err validator.FieldError) []string {
+	return nil
+}
@@ -0,0 +1,84 @@
+package configsvc
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// configcreate is the request body of /configcreate.
+type configcreate struct {
+	App         string `json:"app" validate:"required"`
+	Module      string `json:"module" validate:"required"`
+	Ver         int    `json:"ver" validate:"required"`
+	Config      string `json:"config" validate:"required"`
+	Description string `json:"description"`
+	Parent      string `json:"parent"`
+	Values      []struct {
+		Name  string `json:"name" validate:"required"`
+		Value string `json:"value" validate:"required"`
+	} `json:"values"`
+}
+
+// Config_create: handles the POST /configcreate request
+func Config_create(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_create()")
+
+	var configcreate configcreate
+	err := wscutils.BindJSON(c, &configcreate)
+	if err != nil {
+		l.LogActivity("error while binding json", err)
+		return
+	}
+
+	validationErrors := wscutils.WscValidate(configcreate, getValsForConfigCreateReqError)
+	if len(validationErrors) > 0 {
+		l.LogDebug("Validation errors:", validationErrors)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermWrite, configcreate.App, configcreate.Module, configcreate.Config) {
+		return
+	}
+	// The new config exposes what it inherits, so its parent must be readable too
+	if configcreate.Parent != "" && !auth.Authorize(c, s, auth.PermRead, configcreate.App, configcreate.Module, configcreate.Parent) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, configcreate.App, configcreate.Module, configcreate.Ver, "")
+	if !ok {
+		return
+	}
+
+	values := make(map[string]string, len(configcreate.Values))
+	for _, v := range configcreate.Values {
+		values[v.Name] = v.Value
+	}
+
+	// CreateConfig checks every required field and value against the schema before writing anything
+	var opts []rigel.CreateOption
+	if configcreate.Parent != "" {
+		opts = append(opts, rigel.WithParent(configcreate.Parent))
+	}
+	err = r.CreateConfig(utils.RequestContext(c), configcreate.Config, configcreate.Description, values, opts...)
+	var exists *rigel.ConfigExistsError
+	if errors.As(err, &exists) {
+		field := "config"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeConfigExists, &field, exists.Config)}))
+		return
+	}
+	if err != nil {
+		l.LogActivity("error while creating config:", err)
+		sendSetError(c, err)
+		return
+	}
+	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "config created successfully", Messages: []wscutils.ErrorMessage{}})
+}
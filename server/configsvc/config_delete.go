@@ -0,0 +1,76 @@
+package configsvc
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// configdelete is the request body of /configdelete.
+// If Key is empty the whole named config is deleted.
+type configdelete struct {
+	App    string `json:"app" validate:"required"`
+	Module string `json:"module" validate:"required"`
+	Ver    int    `json:"ver" validate:"required"`
+	Config string `json:"config" validate:"required"`
+	Key    string `json:"key"`
+}
+
+// Config_delete: handles the POST /configdelete request
+func Config_delete(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_delete()")
+
+	var configdelete configdelete
+	err := wscutils.BindJSON(c, &configdelete)
+	if err != nil {
+		l.LogActivity("error while binding json", err)
+		return
+	}
+
+	validationErrors := wscutils.WscValidate(configdelete, configdelete.getVals)
+	if len(validationErrors) > 0 {
+		l.LogDebug("Validation errors:", validationErrors)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermWrite, configdelete.App, configdelete.Module, configdelete.Config) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, configdelete.App, configdelete.Module, configdelete.Ver, configdelete.Config)
+	if !ok {
+		return
+	}
+
+	if configdelete.Key == "" {
+		err = r.DeleteConfig(utils.RequestContext(c))
+	} else {
+		err = r.DeleteKey(utils.RequestContext(c), configdelete.Key)
+	}
+	var inUse *rigel.ConfigInUseError
+	if errors.As(err, &inUse) {
+		field := "config"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeConfigInUse, &field, inUse.Children...)}))
+		return
+	}
+	if err != nil {
+		l.LogActivity("error while deleting value in etcd:", err)
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(utils.ErrcodeUnableToDelete))
+		return
+	}
+	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "data deleted successfully", Messages: []wscutils.ErrorMessage{}})
+}
+
+// getVals returns validation error details based on the field and tag.
+func (config *configdelete) getVals(err validator.FieldError) []string {
+	return nil
+}
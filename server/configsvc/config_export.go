@@ -0,0 +1,142 @@
+package configsvc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// ConfigExportReqParams are the query parameters of /configexport.
+// Format is json, the default, or yaml.
+type ConfigExportReqParams struct {
+	App     string `form:"app" binding:"required"`
+	Module  string `form:"module" binding:"required"`
+	Version int    `form:"ver" binding:"required"`
+	Config  string `form:"config" binding:"required"`
+	Format  string `form:"format"`
+}
+
+// configimport is the request body of /configimport. Document is a document written by /configexport
+// or rigelctl config export, either as a JSON object or as a string holding its JSON or YAML text.
+// With DryRun set, the changes are returned but not made.
+type configimport struct {
+	Document json.RawMessage `json:"document" validate:"required"`
+	DryRun   bool            `json:"dry_run"`
+}
+
+// Config_export: handles the GET /configexport request
+// A JSON document is returned as the data of the usual response; a YAML document is returned as is.
+func Config_export(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_export()")
+
+	var queryParams ConfigExportReqParams
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		fields := "app / module / ver / config"
+		l.Error(err).Log("error unmarshalling query paramaeters to struct")
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeMissingRequiredFields, nil, fields)}))
+		return
+	}
+	if queryParams.Format != "" && queryParams.Format != rigel.FormatJSON && queryParams.Format != rigel.FormatYAML {
+		field := "format"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ERRCODE_INVALID_REQUEST, &field, queryParams.Format)}))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermRead, queryParams.App, queryParams.Module, queryParams.Config) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, queryParams.App, queryParams.Module, queryParams.Version, queryParams.Config)
+	if !ok {
+		return
+	}
+
+	doc, err := r.ExportConfig(c)
+	if err != nil {
+		l.LogActivity("error while exporting config:", err)
+		field := "config"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ErrcodeMissing, &field, queryParams.Config)}))
+		return
+	}
+
+	if queryParams.Format == rigel.FormatYAML {
+		b, err := doc.Marshal(rigel.FormatYAML)
+		if err != nil {
+			wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(wscutils.ErrcodeDatabaseError))
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", b)
+		return
+	}
+	wscutils.SendSuccessResponse(c, wscutils.NewSuccessResponse(doc))
+}
+
+// Config_import: handles the POST /configimport request
+func Config_import(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_import()")
+
+	var configimport configimport
+	err := wscutils.BindJSON(c, &configimport)
+	if err != nil {
+		l.LogActivity("error while binding json", err)
+		return
+	}
+
+	validationErrors := wscutils.WscValidate(configimport, configimport.getValsForImport)
+	if len(validationErrors) > 0 {
+		l.LogDebug("Validation errors:", validationErrors)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
+		return
+	}
+
+	// A document sent as a string holds the text of the document
+	text := []byte(configimport.Document)
+	var str string
+	if json.Unmarshal(configimport.Document, &str) == nil {
+		text = []byte(str)
+	}
+	doc, err := rigel.ParseConfigDocument(text)
+	if err != nil {
+		field := "document"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ERRCODE_INVALID_REQUEST, &field, err.Error())}))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermWrite, doc.App, doc.Module, doc.Config) {
+		return
+	}
+
+	// A client of its own for the config the document names
+	r, ok := utils.RigelClient(c, s, doc.App, doc.Module, doc.Version, doc.Config)
+	if !ok {
+		return
+	}
+
+	var diff *rigel.ConfigDiff
+	if configimport.DryRun {
+		diff, err = r.PreviewImport(c, doc)
+	} else {
+		diff, err = r.ImportConfig(utils.RequestContext(c), doc)
+	}
+	if err != nil {
+		l.LogActivity("error while importing config:", err)
+		sendSetError(c, err)
+		return
+	}
+	wscutils.SendSuccessResponse(c, wscutils.NewSuccessResponse(map[string]any{"applied": !configimport.DryRun, "changes": diff}))
+}
+
+// getValsForImport returns validation error details based on the field and tag.
+func (config *configimport) getValsForImport(err validator.FieldError) []string {
+	return nil
+}
@@ -0,0 +1,84 @@
+package configsvc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// ConfigHistoryReqParams are the query parameters of /confighistory.
+// If Key is empty the history of every key of the named config is returned.
+type ConfigHistoryReqParams struct {
+	App     string `form:"app" binding:"required"`
+	Module  string `form:"module" binding:"required"`
+	Version int    `form:"ver" binding:"required"`
+	Config  string `form:"config" binding:"required"`
+	Key     string `form:"key"`
+}
+
+type historyEntry struct {
+	Key     string    `json:"key"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor,omitempty"`
+	Rev     int64     `json:"rev"`
+}
+
+// Config_history: handles the GET /confighistory request
+func Config_history(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_history()")
+
+	var queryParams ConfigHistoryReqParams
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		fields := "app / module / ver / config"
+		l.Error(err).Log("error unmarshalling query paramaeters to struct")
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeMissingRequiredFields, nil, fields)}))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermRead, queryParams.App, queryParams.Module, queryParams.Config) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, queryParams.App, queryParams.Module, queryParams.Version, queryParams.Config)
+	if !ok {
+		return
+	}
+
+	entries, err := r.History(c, queryParams.Key)
+	if err != nil {
+		l.LogActivity("error while getting history from etcd:", err)
+		var notFound *rigel.KeyNotFoundError
+		if errors.As(err, &notFound) {
+			field := "key"
+			wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ErrcodeMissing, &field, queryParams.Key)}))
+			return
+		}
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(wscutils.ErrcodeDatabaseError))
+		return
+	}
+
+	response := make([]historyEntry, len(entries))
+	for i, e := range entries {
+		response[i] = historyEntry{
+			Key:     e.Key,
+			Old:     e.OldValue,
+			New:     e.NewValue,
+			Deleted: e.Deleted,
+			Time:    e.Time,
+			Actor:   e.Actor,
+			Rev:     e.Revision,
+		}
+	}
+	wscutils.SendSuccessResponse(c, wscutils.NewSuccessResponse(map[string]any{"history": response}))
+}
@@ -0,0 +1,63 @@
+package configsvc
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// ConfigResolveReqParams are the query parameters of /configresolve.
+type ConfigResolveReqParams struct {
+	App     string `form:"app" binding:"required"`
+	Module  string `form:"module" binding:"required"`
+	Version int    `form:"ver" binding:"required"`
+	Config  string `form:"config" binding:"required"`
+}
+
+// Config_resolve: handles the GET /configresolve request
+// It returns the effective value of every field of the named config and the config it comes from.
+func Config_resolve(c *gin.Context, s *service.Service) {
+	l := s.LogHarbour
+	l.Log("Starting execution of Config_resolve()")
+
+	var queryParams ConfigResolveReqParams
+	if err := c.ShouldBindQuery(&queryParams); err != nil {
+		fields := "app / module / ver / config"
+		l.Error(err).Log("error unmarshalling query paramaeters to struct")
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeMissingRequiredFields, nil, fields)}))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermRead, queryParams.App, queryParams.Module, queryParams.Config) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, queryParams.App, queryParams.Module, queryParams.Version, queryParams.Config)
+	if !ok {
+		return
+	}
+
+	resolved, err := r.ResolveConfig(c)
+	if err != nil {
+		l.LogActivity("error while resolving config:", err)
+		field := "config"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ErrcodeMissing, &field, queryParams.Config)}))
+		return
+	}
+
+	// Inherited values are only shown to callers who may read the configs they come from
+	checked := map[string]bool{queryParams.Config: true}
+	for _, rv := range resolved {
+		if rv.Config == "" || checked[rv.Config] {
+			continue
+		}
+		if !auth.Authorize(c, s, auth.PermRead, queryParams.App, queryParams.Module, rv.Config) {
+			return
+		}
+		checked[rv.Config] = true
+	}
+	wscutils.SendSuccessResponse(c, wscutils.NewSuccessResponse(resolved))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/remiges-tech/alya/service"
 	"github.com/remiges-tech/alya/wscutils"
 	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
 	"github.com/remiges-tech/rigel/server/utils"
 )
 
@@ -16,6 +17,7 @@ type configset struct {
 	Config string `json:"config" validate:"required"`
 	Key    string `json:"key" validate:"required"`
 	Value  string `json:"value" validate:"required"`
+	Rev    *int64 `json:"rev,omitempty"` // Rev, if given, is the revision returned by /configget; the value is only set if it has not changed since
 }
 
 type configupdate struct {
@@ -27,6 +29,7 @@ type configupdate struct {
 	Values      []struct {
 		Name  string `json:"name" validate:"required"`
 		Value string `json:"value" validate:"required"`
+		Rev   *int64 `json:"rev,omitempty"` // Rev, if given, is the revision returned by /configget; see configset
 	} `json:"values" validate:"required"`
 }
 
@@ -48,20 +51,23 @@ func Config_set(c *gin.Context, s *service.Service) {
 		return
 	}
 
-	// Extracting Rigel client from service dependency and initializing with values from request parameters.
-	rigelClient := s.Dependencies["rigel"]
-	r, ok := rigelClient.(*rigel.Rigel)
+	if !auth.Authorize(c, s, auth.PermWrite, configset.App, configset.Module, configset.Config) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, configset.App, configset.Module, configset.Ver, configset.Config)
 	if !ok {
-		str := "rigelClient"
-		l.Debug0().LogDebug("Invalid Rigel Client Dependency:", validationErrors)
-		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &str)}))
 		return
 	}
-	r.WithApp(configset.App).WithModule(configset.Module).WithVersion(configset.Ver).WithConfig(configset.Config)
-	err = r.Set(c, configset.Key, configset.Value)
+	var opts []rigel.SetOption
+	if configset.Rev != nil {
+		opts = append(opts, rigel.ExpectRevision(*configset.Rev))
+	}
+	err = r.Set(utils.RequestContext(c), configset.Key, configset.Value, opts...)
 	if err != nil {
 		l.LogActivity("error while setting value in etcd:", err)
-		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse("unable_to_set"))
+		sendSetError(c, err)
 		return
 	} else {
 		wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "data set successfully", Messages: []wscutils.ErrorMessage{}})
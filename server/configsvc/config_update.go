@@ -1,12 +1,16 @@
 package configsvc
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/remiges-tech/rigel"
-	"github.com/remiges-tech/rigel/server/utils"
 	"github.com/remiges-tech/alya/service"
 	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
 )
 
 func Config_update(c *gin.Context, s *service.Service) {
@@ -28,28 +32,109 @@ func Config_update(c *gin.Context, s *service.Service) {
 		return
 	}
 
-	// Extracting Rigel client from service dependency and initializing with values from request parameters.
-	rigelClient := s.Dependencies["rigel"]
-	r, ok := rigelClient.(*rigel.Rigel)
+	if !auth.Authorize(c, s, auth.PermWrite, configupdate.App, configupdate.Module, configupdate.Config) {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	r, ok := utils.RigelClient(c, s, configupdate.App, configupdate.Module, configupdate.Ver, configupdate.Config)
 	if !ok {
-		str := "rigelClient"
-		l.Debug0().LogDebug("Invalid Rigel Client Dependency:", rigelClient)
-		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &str)}))
 		return
 	}
-	r.WithApp(configupdate.App).WithModule(configupdate.Module).WithVersion(configupdate.Ver).WithConfig(configupdate.Config)
 
+	values := make(map[string]string, len(configupdate.Values))
+	revisions := make(map[string]int64)
 	for _, v := range configupdate.Values {
-		err = r.Set(c, v.Name, v.Value)
-		if err != nil {
-			l.LogActivity("error while setting value in etcd:", err)
-			wscutils.SendErrorResponse(c, wscutils.NewErrorResponse("unable_to_set"))
-			return
+		values[v.Name] = v.Value
+		if v.Rev != nil {
+			revisions[v.Name] = *v.Rev
 		}
 	}
+
+	// SetMany validates every value first and stores them in a single transaction, so an update is all-or-nothing.
+	// A config that does not exist yet is created, as long as no required field is left without a value.
+	err = r.SetMany(utils.RequestContext(c), values, rigel.ExpectRevisions(revisions))
+	if err != nil {
+		l.LogActivity("error while setting values in etcd:", err)
+		sendSetError(c, err)
+		return
+	}
 	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "data set successfully", Messages: []wscutils.ErrorMessage{}})
 }
 
+// sendSetError sends the error response for a failed Set, SetMany or CreateConfig.
+// Missing required fields are reported by name, and every invalid value gets its own message naming
+// the field, with the constraint's limit and the value as vals. A conflicting edit names the field,
+// with the expected and current revisions as vals. Anything else is reported as unable_to_set.
+func sendSetError(c *gin.Context, err error) {
+	var missing *rigel.MissingRequiredFieldsError
+	if errors.As(err, &missing) {
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeMissingRequiredFields, nil, missing.Fields...)}))
+		return
+	}
+
+	var tooMany *rigel.TooManyChangesError
+	if errors.As(err, &tooMany) {
+		vals := []string{strconv.Itoa(tooMany.Keys), strconv.Itoa(tooMany.Max)}
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeTooManyChanges, nil, vals...)}))
+		return
+	}
+
+	var conflict *rigel.ConflictError
+	if errors.As(err, &conflict) {
+		field := conflict.Key
+		vals := []string{strconv.FormatInt(conflict.ExpectedRevision, 10), strconv.FormatInt(conflict.ModRevision, 10)}
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.ErrcodeConflict, &field, vals...)}))
+		return
+	}
+
+	var invalidValues rigel.ValidationErrors
+	var invalidValue *rigel.ValidationError
+	switch {
+	case errors.As(err, &invalidValues):
+	case errors.As(err, &invalidValue):
+		invalidValues = rigel.ValidationErrors{invalidValue}
+	}
+	if len(invalidValues) > 0 {
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrorMessages(invalidValues)))
+		return
+	}
+	wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(utils.ErrcodeUnableToSet))
+}
+
+// validationErrcodes maps each check reported by rigel.ValidationError to its errcode.
+var validationErrcodes = map[string]string{
+	rigel.ConstraintType:         utils.ErrcodeInvalidType,
+	rigel.ConstraintMin:          utils.ErrcodeValueTooSmall,
+	rigel.ConstraintExclusiveMin: utils.ErrcodeValueTooSmall,
+	rigel.ConstraintMax:          utils.ErrcodeValueTooLarge,
+	rigel.ConstraintExclusiveMax: utils.ErrcodeValueTooLarge,
+	rigel.ConstraintMinLength:    utils.ErrcodeTooShort,
+	rigel.ConstraintMaxLength:    utils.ErrcodeTooLong,
+	rigel.ConstraintPattern:      utils.ErrcodePatternMismatch,
+	rigel.ConstraintMultipleOf:   utils.ErrcodeNotMultipleOf,
+	rigel.ConstraintEnum:         utils.ErrcodeNotInEnum,
+	rigel.ConstraintRules:        utils.ErrcodeInvalidFlagRule,
+}
+
+// validationErrorMessages converts validation errors into one error message per invalid value.
+func validationErrorMessages(errs rigel.ValidationErrors) []wscutils.ErrorMessage {
+	messages := make([]wscutils.ErrorMessage, 0, len(errs))
+	for _, e := range errs {
+		field := e.Field
+		errcode, ok := validationErrcodes[e.Constraint]
+		if !ok {
+			errcode = utils.ErrcodeUnableToSet
+		}
+		vals := []string{e.Limit}
+		if e.Value != "" {
+			vals = append(vals, e.Value)
+		}
+		messages = append(messages, wscutils.BuildErrorMessage(errcode, &field, vals...))
+	}
+	return messages
+}
+
 // validateConfigupdate performs validation for the Configupdate.
 func validateConfigupdate(config configupdate, c *gin.Context) []wscutils.ErrorMessage {
 	// Validate the request body
@@ -11,8 +11,10 @@ import (
 	"github.com/remiges-tech/alya/wscutils"
 	"github.com/remiges-tech/rigel"
 	"github.com/remiges-tech/rigel/etcd"
+	"github.com/remiges-tech/rigel/server/auth"
 	"github.com/remiges-tech/rigel/server/trees"
 	"github.com/remiges-tech/rigel/server/utils"
+	"github.com/remiges-tech/rigel/types"
 )
 
 // getSchemaResponse represents the structure for outgoing  responses.
@@ -42,6 +44,7 @@ type GetConfigRequestParams struct {
 type values struct {
 	Name  string `json:"name,omitempty"`
 	Value string `json:"value,omitempty"`
+	Rev   int64  `json:"rev,omitempty"` // Rev is the mod revision of the value; pass it back to /configset to detect conflicting edits
 }
 
 func Config_get(c *gin.Context, s *service.Service) {
@@ -62,16 +65,20 @@ func Config_get(c *gin.Context, s *service.Service) {
 		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ErrcodeInvalidJson, nil)}))
 		return
 	}
+	if !auth.Authorize(c, s, auth.PermRead, *queryParams.App, *queryParams.Module, *queryParams.Config) {
+		return
+	}
 	keyStr := rigel.GetConfPath(*queryParams.App, *queryParams.Module, queryParams.Version, *queryParams.Config)
 	// keyStr := utils.RIGELPREFIX + "/" + *queryParams.App + "/" + *queryParams.Module + "/" + strconv.Itoa(queryParams.Version) + "/config/" + *queryParams.Config
-	getValue, err := client.GetWithPrefix(c, keyStr)
+	// The trailing slash keeps e.g. "prod" from matching "prod-eu"
+	getValue, err := client.GetWithPrefixAndRevision(c, keyStr+"/")
 	if err != nil {
 		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(wscutils.ErrcodeMissing, nil, err.Error())}))
 		lh.Debug0().LogActivity("error while get data from db error:", err.Error)
 		return
 	}
 	// set response fields
-	bindGetConfigResponse(&response, &getValue)
+	bindGetConfigResponse(&response, getValue)
 
 	lh.Log(fmt.Sprintf("Record found: %v", map[string]any{"key with --prefix": keyStr, "value": response}))
 	wscutils.SendSuccessResponse(c, wscutils.NewSuccessResponse(response))
@@ -82,7 +89,7 @@ func Config_list(c *gin.Context, s *service.Service) {
 	lh := s.LogHarbour
 	lh.Log("Config_list Request Received")
 
-	// Extracting etcdStorage and rigelTree from service dependency.
+	// Extracting etcdStorage from service dependency.
 
 	var queryParams ConfigListReqParams
 	if err := c.ShouldBindQuery(&queryParams); err != nil {
@@ -98,11 +105,11 @@ func Config_list(c *gin.Context, s *service.Service) {
 		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &field)}))
 		return
 	}
-	r := s.Dependencies["rTree"]
-	rTree, ok := r.(*utils.Node)
-	if !ok {
-		field := "rigelTree"
-		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &field)}))
+	// The tree is loaded on each request, so that configs written since startup are listed
+	rTree, err := utils.LoadTree(c, etcd)
+	if err != nil {
+		lh.Error(err).Log("error loading the rigel keys tree")
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(wscutils.ErrcodeDatabaseError))
 		return
 	}
 
@@ -113,6 +120,15 @@ func Config_list(c *gin.Context, s *service.Service) {
 	trees.Process(rTree, container)
 	response := bindConfigListResponse(container.ResponseData, queryParams)
 
+	// Only the named configs the caller may read are listed
+	readable := response[:0]
+	for _, config := range response {
+		if auth.Allowed(c, s, auth.PermRead, config.App, config.Module, config.Config) {
+			readable = append(readable, config)
+		}
+	}
+	response = readable
+
 	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: "success", Data: map[string]any{"configurations": response}, Messages: []wscutils.ErrorMessage{}})
 }
 
@@ -123,11 +139,11 @@ func bindConfigListResponse(container []any, queryParams ConfigListReqParams) []
 		a := v.(trees.GetConfigListResponse)
 		if a.App == queryParams.App && a.Module == queryParams.Module && a.Ver == queryParams.Version {
 			obj := trees.GetConfigListResponse{
-				App:    a.App,
-				Module: a.Module,
-				Ver:    a.Ver,
-				Config: a.Config,
-				// Description: a.Description,
+				App:         a.App,
+				Module:      a.Module,
+				Ver:         a.Ver,
+				Config:      a.Config,
+				Description: a.Description,
 			}
 			response = append(response, obj)
 		}
@@ -137,25 +153,27 @@ func bindConfigListResponse(container []any, queryParams ConfigListReqParams) []
 }
 
 // bindGetConfigResponse is specifically used in Cinfig_get to bing and set the response
-func bindGetConfigResponse(response *getConfigResponse, getValue *map[string]string) {
-	for key, vals := range *getValue {
+func bindGetConfigResponse(response *getConfigResponse, getValue map[string]types.KeyValue) {
+	for key, kv := range getValue {
+		vals := kv.Value
 
 		arry := strings.Split(key, "/")
 		keyStr := arry[len(arry)-1]
-		if strings.EqualFold(keyStr, "description") {
-			response.Description = vals
-			ver, _ := strconv.Atoi(arry[5])
-			response.App = &arry[3]
-			response.Module = &arry[4]
-			response.Version = &ver
-			response.Config = &arry[7]
-			continue
-		} else {
-
+		switch {
+		case len(arry) > 8 && arry[8] == "meta":
+			// The description is config metadata, kept apart from the keys
+			if keyStr == "description" {
+				response.Description = vals
+			}
+		case len(arry) > 8 && arry[8] == "keys":
 			response.Values = append(response.Values, values{
 				Name:  keyStr,
 				Value: vals,
+				Rev:   kv.ModRevision,
 			})
+		default:
+			// History entries are served by /confighistory
+			continue
 		}
 		ver, _ := strconv.Atoi(arry[5])
 		response.App = &arry[3]
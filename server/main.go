@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"net/http"
 
@@ -14,6 +13,8 @@ import (
 	"github.com/remiges-tech/logharbour/logharbour"
 	"github.com/remiges-tech/rigel"
 	"github.com/remiges-tech/rigel/etcd"
+	"github.com/remiges-tech/rigel/server/auditsvc"
+	"github.com/remiges-tech/rigel/server/auth"
 	"github.com/remiges-tech/rigel/server/configsvc"
 	"github.com/remiges-tech/rigel/server/schemaserv"
 	"github.com/remiges-tech/rigel/server/utils"
@@ -24,6 +25,9 @@ type AppConfig struct {
 	EtcdPort      string `json:"etcd_port"`
 	AppServerPort string `json:"app_server_port"`
 	APIPrefix     string `json:"api_prefix"`
+	AuditSink     string `json:"audit_sink"` // comma-separated: storage, file, logharbour
+	AuditFile     string `json:"audit_file"`
+	AuthConfig    string `json:"auth_config"` // path to the tokens, JWT key and roles; auth is off if empty
 }
 
 // LoadConfigFromEnv updates AppConfig with values from environment variables if they exist
@@ -40,10 +44,22 @@ func LoadConfigFromEnv(appConfig *AppConfig) {
 	if apiPrefix := os.Getenv("API_PREFIX"); apiPrefix != "" {
 		appConfig.APIPrefix = apiPrefix
 	}
+	if auditSink := os.Getenv("AUDIT_SINK"); auditSink != "" {
+		appConfig.AuditSink = auditSink
+	}
+	if auditFile := os.Getenv("AUDIT_FILE"); auditFile != "" {
+		appConfig.AuditFile = auditFile
+	}
+	if authConfig := os.Getenv("AUTH_CONFIG"); authConfig != "" {
+		appConfig.AuthConfig = authConfig
+	}
 }
 
 func main() {
-	var appConfig AppConfig
+	appConfig := AppConfig{
+		AuditSink: utils.AuditSinkStorage,
+		AuditFile: "audit.log",
+	}
 
 	// Override config with environment variables if they are set
 	LoadConfigFromEnv(&appConfig)
@@ -84,31 +100,19 @@ func main() {
 		return
 	}
 
-	//Create a new Rigel instance
-	rigelClient := rigel.NewWithStorage(etcdStorage)
-
-	// Create a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), utils.DIALTIMEOUT)
-	defer cancel()
-
-	// Get all keys from etcd
-	allkeys, err := etcdStorage.GetWithPrefix(ctx, "/")
+	// Every change made through the web services is audited
+	auditSink, err := utils.NewAuditSink(appConfig.AuditSink, etcdStorage, appConfig.AuditFile, l)
 	if err != nil {
-		log.Fatalf("etcd interaction failed: %v", err)
-		return
+		log.Fatalf("Failed to create audit sink: %v", err)
 	}
 
-	// Build a Rigel STree
-	rTree := utils.NewNode("")
-	for k, v := range allkeys {
-		rTree.AddPath(k, v)
-	}
+	//Create a new Rigel instance
+	rigelClient := rigel.NewWithStorage(etcdStorage).WithAuditSink(auditSink)
 
 	// Services
 	s := service.NewService(r).
 		WithLogHarbour(l).
 		WithDependency("appConfig", appConfig).
-		WithDependency("rTree", rTree).
 		WithDependency("etcd", etcdStorage).
 		WithDependency("rigel", rigelClient)
 
@@ -116,15 +120,39 @@ func main() {
 
 	apiV1Group := r.Group(appConfig.APIPrefix)
 
+	// Authentication applies to every web service; handlers check permissions against the policy
+	if appConfig.AuthConfig != "" {
+		authenticator, policy, err := auth.LoadConfig(appConfig.AuthConfig)
+		if err != nil {
+			log.Fatalf("Failed to load auth config: %v", err)
+		}
+		s.WithDependency(auth.PolicyDependency, policy)
+		apiV1Group.Use(auth.Middleware(authenticator))
+	} else {
+		l.Warn().Log("AUTH_CONFIG is not set: the web services accept requests from anyone")
+	}
+
 	// Config Services
 	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/configget", configsvc.Config_get)
 	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/configlist", configsvc.Config_list)
 	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/configset", configsvc.Config_set)
 	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/configupdate", configsvc.Config_update)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/configdelete", configsvc.Config_delete)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/configclone", configsvc.Config_clone)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/configcreate", configsvc.Config_create)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/configexport", configsvc.Config_export)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/configimport", configsvc.Config_import)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/confighistory", configsvc.Config_history)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/configresolve", configsvc.Config_resolve)
 
 	// Schema Services
 	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/getschema", schemaserv.HandleGetSchemaRequest)
 	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/schemalist", schemaserv.HandleGetSchemaListRequest)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/schemaadd", schemaserv.HandleAddSchemaRequest)
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodPost, "/schemadelete", schemaserv.HandleDeleteSchemaRequest)
+
+	// Audit Services
+	s.RegisterRouteWithGroup(apiV1Group, http.MethodGet, "/auditlog", auditsvc.AuditLog)
 
 	r.Run(":" + appConfig.AppServerPort)
 	if err != nil {
@@ -3,7 +3,12 @@ package schemaserv
 const (
 
 	//error messages
-	SCHEMA_NOT_FOUND = "schema_not_found"
+	SCHEMA_NOT_FOUND    = "schema_not_found"
+	SCHEMA_IN_USE       = "schema_in_use"
+	SCHEMA_EXISTS       = "schema_exists"
+	INVALID_SCHEMA      = "invalid_schema"
+	SCHEMA_INCOMPATIBLE = "schema_incompatible"
+	VERSION_MISMATCH    = "version_mismatch"
 
 	// validation errors
 	APP_NAME_REQUIRED      = "App Name required"
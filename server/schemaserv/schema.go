@@ -11,6 +11,7 @@ import (
 	"github.com/remiges-tech/alya/wscutils"
 	"github.com/remiges-tech/rigel"
 	"github.com/remiges-tech/rigel/etcd"
+	"github.com/remiges-tech/rigel/server/auth"
 	"github.com/remiges-tech/rigel/server/utils"
 	"github.com/remiges-tech/rigel/types"
 )
@@ -69,18 +70,14 @@ func HandleGetSchemaRequest(c *gin.Context, s *service.Service) {
 		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
 		return
 	}
-	// Extracting Rigel client from service dependency and initializing with values from request parameters.
-	rigelClient := s.Dependencies["rigel"]
-	client, ok := rigelClient.(*rigel.Rigel)
+	if !auth.Authorize(c, s, auth.PermRead, schemaName, schemaModule, "") {
+		return
+	}
+	// A client of its own for the request, so that no other request can point it at another config
+	client, ok := utils.RigelClient(c, s, schemaName, schemaModule, schemaVersion, "")
 	if !ok {
-		str := "rigelClient"
-		lh.Debug0().LogDebug("Invalid Rigel Client Dependency:", validationErrors)
-		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &str)}))
 		return
 	}
-	client.WithApp(schemaName)
-	client.WithModule(schemaModule)
-	client.WithVersion(schemaVersion)
 
 	// Create a context with a timeout
 	ctx, cancel := context.WithTimeout(context.Background(), utils.DIALTIMEOUT)
@@ -166,7 +163,7 @@ type container struct {
 func HandleGetSchemaListRequest(c *gin.Context, s *service.Service) {
 	lh := s.LogHarbour
 	lh.Log("GetSchemaList Request Received")
-	// Extracting etcdStorage and rigelTree from service dependency.
+	// Extracting etcdStorage from service dependency.
 
 	etcd, ok := s.Dependencies["etcd"].(*etcd.EtcdStorage)
 	if !ok {
@@ -174,11 +171,11 @@ func HandleGetSchemaListRequest(c *gin.Context, s *service.Service) {
 		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &field)}))
 		return
 	}
-	r := s.Dependencies["rTree"]
-	rTree, ok := r.(*utils.Node)
-	if !ok {
-		field := "rigelTree"
-		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(utils.INVALID_DEPENDENCY, &field)}))
+	// The tree is loaded on each request, so that schemas added or deleted since startup are listed as they are
+	rTree, err := utils.LoadTree(c, etcd)
+	if err != nil {
+		lh.Error(err).Log("error loading the rigel keys tree")
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(wscutils.ErrcodeDatabaseError))
 		return
 	}
 
@@ -188,6 +185,15 @@ func HandleGetSchemaListRequest(c *gin.Context, s *service.Service) {
 
 	process(rTree, container)
 
+	// Only the schemas the caller may read are listed
+	readable := container.responseData[:0]
+	for _, schema := range container.responseData {
+		if auth.Allowed(c, s, auth.PermRead, schema.App, schema.Module, "") {
+			readable = append(readable, schema)
+		}
+	}
+	container.responseData = readable
+
 	// Log the completion of execution
 	lh.Log("Finished execution of GetSchemaList")
 
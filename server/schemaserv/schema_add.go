@@ -0,0 +1,140 @@
+package schemaserv
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/cmd/rigelctl/rigelctl"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+	"github.com/remiges-tech/rigel/types"
+)
+
+// AddSchemaRequest represents the request body of /schemaadd.
+// Schema has the same format as the files given to rigelctl schema add. A version in the schema
+// must be the same as Version.
+// Schema versions are immutable: an identical schema is accepted without changes, and a changed one
+// is only accepted if Overwrite is set and every named config under the version stays valid.
+type AddSchemaRequest struct {
+	App       string          `json:"app" validate:"required"`
+	Module    string          `json:"module" validate:"required"`
+	Version   int             `json:"ver" validate:"required"`
+	Schema    json.RawMessage `json:"schema" validate:"required"`
+	Overwrite bool            `json:"overwrite"`
+}
+
+// HandleAddSchemaRequest adds a schema version for the given app and module.
+func HandleAddSchemaRequest(c *gin.Context, s *service.Service) {
+	lh := s.LogHarbour
+	lh.Log("AddSchema Request Received")
+
+	var req AddSchemaRequest
+	err := wscutils.BindJSON(c, &req)
+	if err != nil {
+		lh.LogActivity("error while binding json", err)
+		return
+	}
+
+	validationErrors := wscutils.WscValidate(req, req.getValsForAddSchemaError)
+	if len(validationErrors) > 0 {
+		lh.Debug0().LogDebug("Validation errors:", validationErrors)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermSchemaAdmin, req.App, req.Module, "") {
+		return
+	}
+
+	// The schema is checked exactly like rigelctl schema add checks a schema file
+	if err := rigelctl.ValidateSchema(req.Schema); err != nil {
+		lh.LogActivity("invalid schema: ", map[string]any{"error": err.Error()})
+		field := "schema"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(INVALID_SCHEMA, &field, err.Error())}))
+		return
+	}
+	var schema types.Schema
+	if err := json.Unmarshal(req.Schema, &schema); err != nil {
+		lh.LogActivity("error while parsing schema", err)
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(wscutils.ErrcodeInvalidJson))
+		return
+	}
+	// The schema must not say it is another version than the one it is added as
+	if schema.Version != 0 && schema.Version != req.Version {
+		field := "schema"
+		vals := []string{strconv.Itoa(schema.Version), strconv.Itoa(req.Version)}
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(VERSION_MISMATCH, &field, vals...)}))
+		return
+	}
+	schema.Version = req.Version
+
+	// A client of its own for the request, so that no other request can point it at another config
+	client, ok := utils.RigelClient(c, s, req.App, req.Module, req.Version, "")
+	if !ok {
+		return
+	}
+
+	var opts []rigel.AddSchemaOption
+	if req.Overwrite {
+		opts = append(opts, rigel.ForceOverwrite())
+	}
+	err = client.AddSchema(utils.RequestContext(c), schema, opts...)
+	var changed *rigel.SchemaChangedError
+	var incompatible *rigel.SchemaIncompatibleError
+	switch {
+	case errors.As(err, &changed):
+		// The differences from the stored schema go in vals, one per line of the diff
+		field := "schema"
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(SCHEMA_EXISTS, &field, strings.Split(changed.Diff.String(), "\n")...)}))
+		return
+	case errors.As(err, &incompatible):
+		messages := make([]wscutils.ErrorMessage, 0, len(incompatible.Configs))
+		for _, config := range sortedKeys(incompatible.Configs) {
+			config := config
+			messages = append(messages, wscutils.BuildErrorMessage(SCHEMA_INCOMPATIBLE, &config, incompatible.Configs[config]...))
+		}
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, messages))
+		return
+	case err != nil:
+		lh.LogActivity("error occurred while adding schema: ", map[string]any{"error": err.Error()})
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(utils.ErrcodeUnableToSet))
+		return
+	}
+
+	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "schema added successfully", Messages: []wscutils.ErrorMessage{}})
+
+	// Log the completion of execution
+	lh.Log("Finished execution of AddSchema")
+}
+
+// getValsForAddSchemaError returns a slice of strings to be used as vals for a validation error.
+func (req *AddSchemaRequest) getValsForAddSchemaError(err validator.FieldError) []string {
+	var vals []string
+	switch err.Field() {
+	case "App":
+		vals = append(vals, APP_NAME_REQUIRED)
+	case "Module":
+		vals = append(vals, MODULE_NAME_REQUIRED)
+	case "Version":
+		vals = append(vals, VERSION_NAME_REQUIRED)
+	}
+	return vals
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,84 @@
+package schemaserv
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/server/auth"
+	"github.com/remiges-tech/rigel/server/utils"
+)
+
+// DeleteSchemaRequest represents the request body of /schemadelete.
+// Force also deletes the named configs under the schema version.
+type DeleteSchemaRequest struct {
+	App     string `json:"app" validate:"required"`
+	Module  string `json:"module" validate:"required"`
+	Version int    `json:"ver" validate:"required"`
+	Force   bool   `json:"force"`
+}
+
+// HandleDeleteSchemaRequest deletes a schema version based on given app, module and version.
+// It refuses to delete a schema that still has named configs unless force is set.
+func HandleDeleteSchemaRequest(c *gin.Context, s *service.Service) {
+	lh := s.LogHarbour
+	lh.Log("DeleteSchema Request Received")
+
+	var req DeleteSchemaRequest
+	err := wscutils.BindJSON(c, &req)
+	if err != nil {
+		lh.LogActivity("error while binding json", err)
+		return
+	}
+
+	validationErrors := wscutils.WscValidate(req, req.getValsForDeleteSchemaError)
+	if len(validationErrors) > 0 {
+		lh.Debug0().LogDebug("Validation errors:", validationErrors)
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, validationErrors))
+		return
+	}
+
+	if !auth.Authorize(c, s, auth.PermSchemaAdmin, req.App, req.Module, "") {
+		return
+	}
+
+	// A client of its own for the request, so that no other request can point it at another config
+	client, ok := utils.RigelClient(c, s, req.App, req.Module, req.Version, "")
+	if !ok {
+		return
+	}
+
+	err = client.DeleteSchema(utils.RequestContext(c), req.Force)
+	if err != nil {
+		lh.LogActivity("error occurred while deleting schema: ", map[string]any{"error": err.Error()})
+		var inUse *rigel.SchemaInUseError
+		if errors.As(err, &inUse) {
+			wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(SCHEMA_IN_USE, nil, inUse.Configs...)}))
+			return
+		}
+		wscutils.SendErrorResponse(c, wscutils.NewErrorResponse(utils.ErrcodeUnableToDelete))
+		return
+	}
+
+	wscutils.SendSuccessResponse(c, &wscutils.Response{Status: wscutils.SuccessStatus, Data: "schema deleted successfully", Messages: []wscutils.ErrorMessage{}})
+
+	// Log the completion of execution
+	lh.Log("Finished execution of DeleteSchema")
+}
+
+// getValsForDeleteSchemaError returns a slice of strings to be used as vals for a validation error.
+func (req *DeleteSchemaRequest) getValsForDeleteSchemaError(err validator.FieldError) []string {
+	var vals []string
+	switch err.Field() {
+	case "App":
+		vals = append(vals, APP_NAME_REQUIRED)
+	case "Module":
+		vals = append(vals, MODULE_NAME_REQUIRED)
+	case "Version":
+		vals = append(vals, VERSION_NAME_REQUIRED)
+	}
+	return vals
+}
@@ -78,6 +78,12 @@ func workOnVersions(v *utils.Node, rTree *utils.Node, c *Container) {
 }
 
 func workOnConfigs(conf *utils.Node, rTree *utils.Node, c *Container) {
+	// A config with nothing left but its history has been deleted
+	_, hasKeys := conf.Children["keys"]
+	_, hasMeta := conf.Children["meta"]
+	if !hasKeys && !hasMeta {
+		return
+	}
 	c.Config = conf.Name
 	GenerateResponse(c)
 }
@@ -116,8 +122,7 @@ func getConfigDescr(t *Container) string {
 	// Create a context with a timeout
 	ctx, cancel := context.WithTimeout(context.Background(), utils.DIALTIMEOUT)
 	defer cancel()
-	configKeyPath := rigel.GetConfKeyPath(t.appName, t.moduleName, t.version, t.Config, "description")
-	descr, err := t.Etcd.Get(ctx, configKeyPath) // vInt))
+	descr, err := t.Etcd.Get(ctx, rigel.GetConfDescriptionPath(t.appName, t.moduleName, t.version, t.Config))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			wscutils.NewErrorResponse("description get timed out")
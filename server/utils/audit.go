@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/logharbour/logharbour"
+	"github.com/remiges-tech/rigel"
+	"github.com/remiges-tech/rigel/types"
+)
+
+// Audit sinks that can be named in AUDIT_SINK
+const (
+	AuditSinkStorage    = "storage"
+	AuditSinkFile       = "file"
+	AuditSinkLogharbour = "logharbour"
+)
+
+// ActorKey is the key under which the auth middleware stores the caller's name in the gin context
+const ActorKey = "rigel_actor"
+
+// RequestContext returns the context rigel calls made for the request c run with, which records
+// the web services as the source, and the authenticated caller as the actor, of any change made with it.
+func RequestContext(c *gin.Context) context.Context {
+	ctx := rigel.WithSource(c, rigel.SourceAPI)
+	if actor := c.GetString(ActorKey); actor != "" {
+		ctx = rigel.WithActor(ctx, actor)
+	}
+	return ctx
+}
+
+// LogharbourAuditSink writes audit records as data change entries of a logharbour logger.
+// It cannot read records back, so /auditlog needs another sink alongside it.
+type LogharbourAuditSink struct {
+	Logger *logharbour.Logger
+}
+
+// Record logs record as a change of the config key, named config or schema it is about.
+func (s LogharbourAuditSink) Record(ctx context.Context, record rigel.AuditRecord) error {
+	what := rigel.GetSchemaPath(record.App, record.Module, record.Version)
+	if record.Config != "" {
+		what = rigel.GetConfKeyPath(record.App, record.Module, record.Version, record.Config, record.Key)
+	}
+
+	l := s.Logger.WithWho(record.Actor).WithOp(record.Operation).WithWhatClass("rigel").WithWhatInstanceId(what)
+	if record.Outcome == rigel.AuditSuccess {
+		l = l.WithStatus(logharbour.Success)
+	} else {
+		l = l.WithStatus(logharbour.Failure).Error(fmt.Errorf("%s", record.Error))
+	}
+
+	change := logharbour.ChangeInfo{
+		Entity:    record.Source,
+		Operation: record.Operation,
+		Changes:   []logharbour.ChangeDetail{{Field: record.Key, OldValue: record.OldValue, NewValue: record.NewValue}},
+	}
+	l.Info().LogDataChange("rigel audit", change)
+	return nil
+}
+
+// NewAuditSink returns the audit sink for sinks, a comma-separated list of sink names.
+// Records go to every sink listed; /auditlog reads them from the first one that can read them back.
+func NewAuditSink(sinks string, storage types.Storage, auditFile string, logger *logharbour.Logger) (rigel.AuditSink, error) {
+	var auditSinks rigel.AuditSinks
+	for _, name := range strings.Split(sinks, ",") {
+		switch strings.TrimSpace(name) {
+		case AuditSinkStorage:
+			auditSinks = append(auditSinks, rigel.NewStorageAuditSink(storage))
+		case AuditSinkFile:
+			sink, err := rigel.NewFileAuditSink(auditFile)
+			if err != nil {
+				return nil, err
+			}
+			auditSinks = append(auditSinks, sink)
+		case AuditSinkLogharbour:
+			auditSinks = append(auditSinks, LogharbourAuditSink{Logger: logger})
+		default:
+			return nil, fmt.Errorf("unknown audit sink %q", name)
+		}
+	}
+	return auditSinks, nil
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/remiges-tech/alya/service"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/rigel"
+)
+
+// RigelClient returns a new Rigel client for the named config of a request, sharing the storage and
+// audit sink of the server's client. Handlers must never point the shared client at the config of a
+// request: another request could point it elsewhere between the authorization check and the call.
+// If the server has no Rigel client, RigelClient sends the error response and returns false.
+func RigelClient(c *gin.Context, s *service.Service, app, module string, version int, config string) (*rigel.Rigel, bool) {
+	shared, ok := s.Dependencies["rigel"].(*rigel.Rigel)
+	if !ok {
+		str := "rigelClient"
+		s.LogHarbour.Debug0().LogDebug("Invalid Rigel Client Dependency:", s.Dependencies["rigel"])
+		wscutils.SendErrorResponse(c, wscutils.NewResponse(wscutils.ErrorStatus, nil, []wscutils.ErrorMessage{wscutils.BuildErrorMessage(INVALID_DEPENDENCY, &str)}))
+		return nil, false
+	}
+	return rigel.New(shared.Storage, app, module, version, config).WithAuditSink(shared.AuditSink()), true
+}
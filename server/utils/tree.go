@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// LoadTree builds the tree of all Rigel keys as they are in storage now. The list handlers load
+// it on each request, so that configs and schemas created or deleted since startup are listed
+// as they are.
+func LoadTree(ctx context.Context, storage types.Storage) (*Node, error) {
+	keys, err := storage.GetWithPrefix(ctx, RIGELPREFIX+"/")
+	if err != nil {
+		return nil, err
+	}
+	tree := NewNode("")
+	for k, v := range keys {
+		tree.AddPath(k, v)
+	}
+	return tree, nil
+}
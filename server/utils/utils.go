@@ -12,6 +12,25 @@ const (
 	RIGELPREFIX                  = "/remiges/rigel"
 	INVALID_DEPENDENCY           = "invalid_dependency"
 	ErrcodeMissingRequiredFields = "missing_required_fields"
+	ErrcodeUnableToDelete        = "unable_to_delete"
+	ErrcodeUnableToSet           = "unable_to_set"
+	ErrcodeConflict              = "conflict"
+	ErrcodeAuditLogUnavailable   = "audit_log_unavailable"
+	ErrcodeForbidden             = "forbidden"
+	ErrcodeConfigExists          = "config_exists"
+	ErrcodeConfigInUse           = "config_in_use"
+	ErrcodeTooManyChanges        = "too_many_changes"
+
+	// Validation errcodes, one per kind of check a value can fail
+	ErrcodeInvalidType     = "invalid_type"
+	ErrcodeValueTooSmall   = "value_too_small"
+	ErrcodeValueTooLarge   = "value_too_large"
+	ErrcodeTooShort        = "too_short"
+	ErrcodeTooLong         = "too_long"
+	ErrcodePatternMismatch = "pattern_mismatch"
+	ErrcodeNotMultipleOf   = "not_multiple_of"
+	ErrcodeNotInEnum       = "not_in_enum"
+	ErrcodeInvalidFlagRule = "invalid_flag_rule"
 )
 
 type Node struct {
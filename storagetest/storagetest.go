@@ -0,0 +1,534 @@
+// Package storagetest provides a conformance test suite for implementations of the
+// Storage interface defined in the Rigel project. Every Storage backend is expected
+// to pass it, so that code written against one backend behaves the same on another.
+//
+// A backend's own tests call Run with a function that returns a fresh, empty storage:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) types.Storage {
+//			return memstore.New()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/rigel/types"
+)
+
+// eventTimeout is how long the suite waits for a watch event before failing.
+const eventTimeout = 2 * time.Second
+
+// NewStorage returns a fresh, empty Storage for a single test.
+// Any cleanup should be registered with t.Cleanup.
+type NewStorage func(t *testing.T) types.Storage
+
+// Run runs the conformance suite against the storage returned by newStorage.
+func Run(t *testing.T, newStorage NewStorage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s types.Storage)
+	}{
+		{"GetNonExistentKey", testGetNonExistentKey},
+		{"PutGet", testPutGet},
+		{"PutOverwrite", testPutOverwrite},
+		{"GetWithPrefix", testGetWithPrefix},
+		{"GetWithRevision", testGetWithRevision},
+		{"GetWithPrefixAndRevision", testGetWithPrefixAndRevision},
+		{"Delete", testDelete},
+		{"DeleteNonExistentKey", testDeleteNonExistentKey},
+		{"DeleteWithPrefix", testDeleteWithPrefix},
+		{"Txn", testTxn},
+		{"TxnDuplicateKey", testTxnDuplicateKey},
+		{"TxnTooLarge", testTxnTooLarge},
+		{"TxnIfModRevision", testTxnIfModRevision},
+		{"TxnConflict", testTxnConflict},
+		{"WatchPrefix", testWatchPrefix},
+		{"WatchIgnoresOtherPrefixes", testWatchIgnoresOtherPrefixes},
+		{"WatchMultipleWatchers", testWatchMultipleWatchers},
+		{"WatchOrder", testWatchOrder},
+		{"WatchEventDetails", testWatchEventDetails},
+		{"WatchDeleteWithPrefix", testWatchDeleteWithPrefix},
+		{"WatchTxn", testWatchTxn},
+		{"WatchClosesOnCancel", testWatchClosesOnCancel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+func testGetNonExistentKey(t *testing.T, s types.Storage) {
+	value, err := s.Get(context.Background(), "/conformance/non-existent-key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "" {
+		t.Errorf("Expected an empty string, got '%s'", value)
+	}
+}
+
+func testPutGet(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/key", "value")
+
+	value, err := s.Get(context.Background(), "/conformance/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "value" {
+		t.Errorf("Expected 'value', got '%s'", value)
+	}
+}
+
+func testPutOverwrite(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/key", "value1")
+	mustPut(t, s, "/conformance/key", "value2")
+
+	value, err := s.Get(context.Background(), "/conformance/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "value2" {
+		t.Errorf("Expected 'value2', got '%s'", value)
+	}
+}
+
+func testGetWithPrefix(t *testing.T, s types.Storage) {
+	prefixV1 := "/conformance/app/module/1/"
+	prefixV2 := "/conformance/app/module/2/"
+	mustPut(t, s, prefixV1+"key1", "value1")
+	mustPut(t, s, prefixV1+"key2", "value2")
+	mustPut(t, s, prefixV2+"key1", "value3")
+
+	keyVal, err := s.GetWithPrefix(context.Background(), prefixV1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 2 || keyVal[prefixV1+"key1"] != "value1" || keyVal[prefixV1+"key2"] != "value2" {
+		t.Errorf("Expected only the keys under '%s', got %v", prefixV1, keyVal)
+	}
+
+	keyVal, err = s.GetWithPrefix(context.Background(), "/conformance/missing/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 0 {
+		t.Errorf("Expected an empty map, got %v", keyVal)
+	}
+}
+
+func testGetWithRevision(t *testing.T, s types.Storage) {
+	kv, err := s.GetWithRevision(context.Background(), "/conformance/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if kv.Value != "" || kv.ModRevision != 0 {
+		t.Errorf("Expected an empty value and revision 0 for a missing key, got %+v", kv)
+	}
+
+	mustPut(t, s, "/conformance/key", "value1")
+	first, err := s.GetWithRevision(context.Background(), "/conformance/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first.Value != "value1" || first.ModRevision <= 0 {
+		t.Errorf("Expected 'value1' with a positive revision, got %+v", first)
+	}
+
+	mustPut(t, s, "/conformance/other", "value")
+	mustPut(t, s, "/conformance/key", "value2")
+	second, err := s.GetWithRevision(context.Background(), "/conformance/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if second.Value != "value2" || second.ModRevision <= first.ModRevision {
+		t.Errorf("Expected 'value2' with a revision greater than %d, got %+v", first.ModRevision, second)
+	}
+}
+
+func testGetWithPrefixAndRevision(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/key1", "value1")
+	mustPut(t, s, "/conformance/config/key2", "value2")
+	mustPut(t, s, "/conformance/other/key", "value3")
+
+	keyVal, err := s.GetWithPrefixAndRevision(context.Background(), "/conformance/config/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	key1, key2 := keyVal["/conformance/config/key1"], keyVal["/conformance/config/key2"]
+	if len(keyVal) != 2 || key1.Value != "value1" || key2.Value != "value2" {
+		t.Fatalf("Expected only the keys under '/conformance/config/', got %v", keyVal)
+	}
+	if key1.ModRevision <= 0 || key2.ModRevision <= key1.ModRevision {
+		t.Errorf("Expected increasing positive revisions, got %d and %d", key1.ModRevision, key2.ModRevision)
+	}
+}
+
+func testDelete(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/key", "value")
+
+	if err := s.Delete(context.Background(), "/conformance/key"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	value, err := s.Get(context.Background(), "/conformance/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "" {
+		t.Errorf("Expected an empty string after delete, got '%s'", value)
+	}
+}
+
+func testDeleteNonExistentKey(t *testing.T, s types.Storage) {
+	if err := s.Delete(context.Background(), "/conformance/non-existent-key"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func testDeleteWithPrefix(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/prod/key1", "value1")
+	mustPut(t, s, "/conformance/config/prod/key2", "value2")
+	mustPut(t, s, "/conformance/config/prod-eu/key1", "value3")
+
+	if err := s.DeleteWithPrefix(context.Background(), "/conformance/config/prod/"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	keyVal, err := s.GetWithPrefix(context.Background(), "/conformance/config/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 1 || keyVal["/conformance/config/prod-eu/key1"] != "value3" {
+		t.Errorf("Expected only '/conformance/config/prod-eu/key1' to remain, got %v", keyVal)
+	}
+}
+
+func testTxn(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/key1", "old")
+	mustPut(t, s, "/conformance/config/key3", "value3")
+
+	ops := []types.Op{
+		types.PutOp("/conformance/config/key1", "value1"),
+		types.PutOp("/conformance/config/key2", "value2"),
+		types.DeleteOp("/conformance/config/key3"),
+		types.DeleteOp("/conformance/config/non-existent-key"),
+	}
+	if err := s.Txn(context.Background(), ops); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	keyVal, err := s.GetWithPrefix(context.Background(), "/conformance/config/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 2 || keyVal["/conformance/config/key1"] != "value1" || keyVal["/conformance/config/key2"] != "value2" {
+		t.Errorf("Expected key1 and key2 to be set and key3 deleted, got %v", keyVal)
+	}
+}
+
+func testTxnDuplicateKey(t *testing.T, s types.Storage) {
+	ops := []types.Op{
+		types.PutOp("/conformance/config/key", "value1"),
+		types.PutOp("/conformance/config/key", "value2"),
+	}
+	if err := s.Txn(context.Background(), ops); err == nil {
+		t.Fatalf("Expected an error for a key given twice, got nil")
+	}
+
+	value, err := s.Get(context.Background(), "/conformance/config/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "" {
+		t.Errorf("Expected nothing to be written, got '%s'", value)
+	}
+}
+
+func testTxnTooLarge(t *testing.T, s types.Storage) {
+	ops := make([]types.Op, types.MaxTxnOps+1)
+	for i := range ops {
+		ops[i] = types.PutOp(fmt.Sprintf("/conformance/config/key%d", i), "value")
+	}
+	var tooLarge *types.TxnTooLargeError
+	if err := s.Txn(context.Background(), ops); !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected a TxnTooLargeError, got %v", err)
+	}
+
+	keyVal, err := s.GetWithPrefix(context.Background(), "/conformance/config/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 0 {
+		t.Errorf("Expected nothing to be written, got %d keys", len(keyVal))
+	}
+
+	if err := s.Txn(context.Background(), ops[:types.MaxTxnOps]); err != nil {
+		t.Errorf("Expected %d ops to be applied, got %v", types.MaxTxnOps, err)
+	}
+}
+
+func testTxnIfModRevision(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/key", "value1")
+	kv, err := s.GetWithRevision(context.Background(), "/conformance/config/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ops := []types.Op{
+		types.PutOp("/conformance/config/key", "value2").IfModRevision(kv.ModRevision),
+		// A revision of 0 means the key must not exist yet
+		types.PutOp("/conformance/config/new", "value").IfModRevision(0),
+	}
+	if err := s.Txn(context.Background(), ops); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	value, err := s.Get(context.Background(), "/conformance/config/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != "value2" {
+		t.Errorf("Expected 'value2', got '%s'", value)
+	}
+}
+
+func testTxnConflict(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/key", "value1")
+	stale, err := s.GetWithRevision(context.Background(), "/conformance/config/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mustPut(t, s, "/conformance/config/key", "value2")
+	current, err := s.GetWithRevision(context.Background(), "/conformance/config/key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ops := []types.Op{
+		types.PutOp("/conformance/config/other", "value"),
+		types.PutOp("/conformance/config/key", "value3").IfModRevision(stale.ModRevision),
+	}
+	err = s.Txn(context.Background(), ops)
+	var conflict *types.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a *types.ConflictError, got %v", err)
+	}
+	if conflict.Key != "/conformance/config/key" || conflict.ExpectedRevision != stale.ModRevision || conflict.ModRevision != current.ModRevision {
+		t.Errorf("Expected a conflict on '/conformance/config/key' at revision %d, got %+v", current.ModRevision, *conflict)
+	}
+
+	keyVal, err := s.GetWithPrefix(context.Background(), "/conformance/config/")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keyVal) != 1 || keyVal["/conformance/config/key"] != "value2" {
+		t.Errorf("Expected nothing to be written, got %v", keyVal)
+	}
+
+	// A key that must not exist, but does
+	err = s.Txn(context.Background(), []types.Op{types.PutOp("/conformance/config/key", "value3").IfModRevision(0)})
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected a *types.ConflictError, got %v", err)
+	}
+}
+
+func testWatchPrefix(t *testing.T, s types.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mustPut(t, s, "/conformance/config/key", "value")
+	expectEvent(t, events, "/conformance/config/key", "value")
+}
+
+func testWatchIgnoresOtherPrefixes(t *testing.T, s types.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mustPut(t, s, "/conformance/other/key", "ignored")
+	mustPut(t, s, "/conformance/config/key", "value")
+
+	// The first event seen must be the one under the watched prefix
+	expectEvent(t, events, "/conformance/config/key", "value")
+}
+
+func testWatchMultipleWatchers(t *testing.T, s types.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events1 := make(chan types.Event)
+	events2 := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := s.Watch(ctx, "/conformance/config/key", events2); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mustPut(t, s, "/conformance/config/key", "value")
+	expectEvent(t, events1, "/conformance/config/key", "value")
+	expectEvent(t, events2, "/conformance/config/key", "value")
+}
+
+func testWatchOrder(t *testing.T, s types.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mustPut(t, s, "/conformance/config/key", "value1")
+	mustPut(t, s, "/conformance/config/key", "value2")
+	mustPut(t, s, "/conformance/config/key", "value3")
+	expectEvent(t, events, "/conformance/config/key", "value1")
+	expectEvent(t, events, "/conformance/config/key", "value2")
+	expectEvent(t, events, "/conformance/config/key", "value3")
+}
+
+func testWatchEventDetails(t *testing.T, s types.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mustPut(t, s, "/conformance/config/key", "value1")
+	created := expectEvent(t, events, "/conformance/config/key", "value1")
+	if created.Type != types.EventPut || created.PrevValue != "" {
+		t.Errorf("Expected a PUT event with no previous value, got %v with previous value '%s'", created.Type, created.PrevValue)
+	}
+
+	mustPut(t, s, "/conformance/config/key", "value2")
+	updated := expectEvent(t, events, "/conformance/config/key", "value2")
+	if updated.Type != types.EventPut || updated.PrevValue != "value1" {
+		t.Errorf("Expected a PUT event with previous value 'value1', got %v with previous value '%s'", updated.Type, updated.PrevValue)
+	}
+
+	if err := s.Delete(context.Background(), "/conformance/config/key"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	deleted := expectEvent(t, events, "/conformance/config/key", "")
+	if deleted.Type != types.EventDelete || deleted.PrevValue != "value2" {
+		t.Errorf("Expected a DELETE event with previous value 'value2', got %v with previous value '%s'", deleted.Type, deleted.PrevValue)
+	}
+
+	if !(created.ModRevision > 0 && created.ModRevision < updated.ModRevision && updated.ModRevision < deleted.ModRevision) {
+		t.Errorf("Expected increasing mod revisions, got %d, %d, %d", created.ModRevision, updated.ModRevision, deleted.ModRevision)
+	}
+}
+
+func testWatchDeleteWithPrefix(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/key1", "value1")
+	mustPut(t, s, "/conformance/config/key2", "value2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := s.DeleteWithPrefix(context.Background(), "/conformance/config/"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	first := expectEvent(t, events, "/conformance/config/key1", "")
+	second := expectEvent(t, events, "/conformance/config/key2", "")
+	if first.Type != types.EventDelete || second.Type != types.EventDelete {
+		t.Errorf("Expected DELETE events, got %v and %v", first.Type, second.Type)
+	}
+	if first.ModRevision != second.ModRevision {
+		t.Errorf("Expected keys deleted together to share a revision, got %d and %d", first.ModRevision, second.ModRevision)
+	}
+}
+
+func testWatchTxn(t *testing.T, s types.Storage) {
+	mustPut(t, s, "/conformance/config/key2", "value2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ops := []types.Op{
+		types.PutOp("/conformance/config/key1", "value1"),
+		types.DeleteOp("/conformance/config/key2"),
+	}
+	if err := s.Txn(context.Background(), ops); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	put := expectEvent(t, events, "/conformance/config/key1", "value1")
+	deleted := expectEvent(t, events, "/conformance/config/key2", "")
+	if put.Type != types.EventPut || deleted.Type != types.EventDelete {
+		t.Errorf("Expected a PUT and a DELETE event, got %v and %v", put.Type, deleted.Type)
+	}
+	if put.ModRevision != deleted.ModRevision {
+		t.Errorf("Expected changes made in one transaction to share a revision, got %d and %d", put.ModRevision, deleted.ModRevision)
+	}
+}
+
+func testWatchClosesOnCancel(t *testing.T, s types.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events := make(chan types.Event)
+	if err := s.Watch(ctx, "/conformance/config/", events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("Expected the events channel to be closed, got an event")
+		}
+	case <-time.After(eventTimeout):
+		t.Fatalf("Expected the events channel to be closed after cancel")
+	}
+}
+
+func mustPut(t *testing.T, s types.Storage, key, value string) {
+	t.Helper()
+	if err := s.Put(context.Background(), key, value); err != nil {
+		t.Fatalf("Expected no error putting '%s', got %v", key, err)
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan types.Event, key, value string) types.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.Key != key || event.Value != value {
+			t.Errorf("Expected event with key '%s' and value '%s', got key '%s' and value '%s'", key, value, event.Key, event.Value)
+		}
+		return event
+	case <-time.After(eventTimeout):
+		t.Fatalf("Expected to receive an event for key '%s', but didn't", key)
+	}
+	return types.Event{}
+}
@@ -3,6 +3,7 @@ package types
 
 import (
 	"context"
+	"fmt"
 )
 
 // Schema represents the structure of a schema. Currently, the only supported type is JSON.
@@ -27,28 +28,70 @@ type Schema struct {
 	Description string  // Description provides more information about the schema
 }
 
+// Field types supported by Rigel. Values are always stored as strings; the type decides
+// how a value is validated and what Go type it is converted to.
+const (
+	TypeInt        = "int"               // int
+	TypeFloat      = "float"             // float64
+	TypeString     = "string"            // string
+	TypeBool       = "bool"              // bool, as accepted by strconv.ParseBool
+	TypeDuration   = "duration"          // time.Duration, stored in time.ParseDuration format such as "1m30s"
+	TypeStringList = "[]string"          // []string, stored as a JSON array
+	TypeIntList    = "[]int"             // []int, stored as a JSON array
+	TypeStringMap  = "map[string]string" // map[string]string, stored as a JSON object
+	TypeJSON       = "json"              // json.RawMessage, any JSON document
+	TypeURL        = "url"               // string holding an absolute URL with a scheme and a host
+	TypeSecret     = "secret"            // string that should not be displayed
+	TypeFlag       = "flag"              // rigel.Flag, a feature flag with targeting rules, stored as a JSON object
+)
+
+// Constraints restricts the values a field accepts. Constraints that do not apply to the field's type are ignored.
 type Constraints struct {
-	Min  *int     `json:"min,omitempty"`
-	Max  *int     `json:"max,omitempty"`
+	// Min and Max bound the value of int, float and duration fields; durations are compared in seconds.
+	// For string, url, secret, list and map fields they bound the length instead, unless MinLength or
+	// MaxLength is set. This keeps schemas written before MinLength and MaxLength existed working.
+	Min          *float64 `json:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty"`
+	ExclusiveMin bool     `json:"exclusiveMin,omitempty"` // ExclusiveMin makes Min a strict lower bound on the value
+	ExclusiveMax bool     `json:"exclusiveMax,omitempty"` // ExclusiveMax makes Max a strict upper bound on the value
+
+	// MinLength and MaxLength bound the number of characters of string, url and secret fields,
+	// and the number of elements of list and map fields.
+	MinLength *int `json:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty"`
+
+	// Pattern is a regular expression, in Go's RE2 syntax, that string, url and secret values and the
+	// elements of []string values must match. As in JSON Schema it is not anchored; use ^ and $ to match the whole value.
+	Pattern string `json:"pattern,omitempty"`
+
+	// MultipleOf requires int, float and duration values to be a multiple of it; durations are compared in seconds.
+	MultipleOf *float64 `json:"multipleOf,omitempty"`
+
+	// Enum lists the allowed values, written in the form they are stored in. For list fields it lists
+	// the allowed elements. It does not apply to map, json and flag fields.
 	Enum []string `json:"enum,omitempty"`
 }
 
-// Field represents a single field in a schema. Currently, the only supported types are string, int, and bool.
+// Field represents a single field in a schema. See the Type constants for the supported types.
 //
 // Example:
 //
 //	{
 //	  "name": "maxConnections",
-//	  "type": "int"
+//	  "type": "int",
+//	  "default": 100,
+//	  "required": true
 //	}
 type Field struct {
 	Name        string       `json:"name"` // Name represents the name of the field (config parameter).
-	Type        string       `json:"type"` // Type represents the type of the field. Currently, the supported types are "string", "int", and "bool".
+	Type        string       `json:"type"` // Type represents the type of the field, one of the Type constants.
 	Description string       `json:"description"`
 	Constraints *Constraints `json:"constraints"`
+	Default     any          `json:"default,omitempty"`  // Default is used when the field has no value in a named config. It must be valid for Type and Constraints.
+	Required    bool         `json:"required,omitempty"` // Required fields must have a value, or a default, in every named config.
 }
 
-// Storage is an interface that abstracts the operations for getting and putting data in
+// Storage is an interface that abstracts the operations for getting, putting and deleting data in
 // Rigel's underlying storage
 type Storage interface {
 	// Get retrieves a value associated with the given key.
@@ -61,18 +104,135 @@ type Storage interface {
 	// If an error occurs during the operation, it is returned.
 	Put(ctx context.Context, key string, value string) error
 
+	// GetWithPrefix retrieves all key-value pairs where the keys start with the given prefix.
+	// If no key matches, it returns an empty map and no error.
+	GetWithPrefix(ctx context.Context, prefix string) (map[string]string, error)
+
+	// GetWithRevision is like Get, but also returns the mod revision of the key, the revision at which
+	// it was last changed. The mod revision of a key that does not exist is 0.
+	GetWithRevision(ctx context.Context, key string) (KeyValue, error)
+
+	// GetWithPrefixAndRevision is like GetWithPrefix, but also returns the mod revision of every key.
+	GetWithPrefixAndRevision(ctx context.Context, prefix string) (map[string]KeyValue, error)
+
+	// Delete removes the given key.
+	// Deleting a key that does not exist is not an error.
+	// If an error occurs during the operation, it is returned.
+	Delete(ctx context.Context, key string) error
+
+	// DeleteWithPrefix removes all keys that start with the given prefix.
+	// If an error occurs during the operation, it is returned.
+	DeleteWithPrefix(ctx context.Context, prefix string) error
+
+	// Txn applies ops atomically: either all of them take effect or none does, and watchers see
+	// them as changes made at the same revision. Each key may appear only once in ops.
+	// Ops created with IfModRevision make Txn a compare-and-swap: if the mod revision of any of their
+	// keys has changed, nothing is applied and Txn returns a *ConflictError.
+	// Txn refuses more than MaxTxnOps ops with a *TxnTooLargeError.
+	// If an error occurs during the operation, it is returned and nothing is changed.
+	Txn(ctx context.Context, ops []Op) error
+
 	// Watch watches for changes to a key in the storage and sends the events to the provided channel.
+	// The key is treated as a prefix, so changes to every key starting with it are reported.
 	// The events includes the key and the updated value.
-	// events is the channel to send events when the key's value changes
+	// events is the channel to send events when the key's value changes.
+	// Implementations close the events channel once ctx is cancelled and the watch has stopped.
 	Watch(ctx context.Context, key string, events chan<- Event) error
 }
 
+// KeyValue is a stored value together with the revision at which it was last changed.
+type KeyValue struct {
+	Value       string
+	ModRevision int64 // ModRevision is 0 if the key does not exist
+}
+
+// Op is a single write applied by Storage.Txn. Use PutOp and DeleteOp to create one.
+type Op struct {
+	Key    string
+	Value  string
+	Delete bool // Delete removes Key instead of storing Value
+
+	// CheckRevision makes the transaction conditional on the mod revision of Key being ModRevision.
+	// A ModRevision of 0 means the key must not exist.
+	CheckRevision bool
+	ModRevision   int64
+}
+
+// IfModRevision returns a copy of op that is only applied if the mod revision of its key is still rev.
+func (op Op) IfModRevision(rev int64) Op {
+	op.CheckRevision = true
+	op.ModRevision = rev
+	return op
+}
+
+// MaxTxnOps is the most ops Storage.Txn applies in one transaction. It is etcd's default limit
+// (--max-txn-ops), which every implementation enforces so that callers find out without etcd.
+const MaxTxnOps = 128
+
+// TxnTooLargeError is returned by Storage.Txn when it is given more than MaxTxnOps ops.
+type TxnTooLargeError struct {
+	Ops int
+}
+
+func (e *TxnTooLargeError) Error() string {
+	return fmt.Sprintf("transaction has %d operations, more than the %d allowed", e.Ops, MaxTxnOps)
+}
+
+// ConflictError is returned by Storage.Txn when a key has been changed since the revision an Op expects.
+type ConflictError struct {
+	Key              string
+	ExpectedRevision int64
+	ModRevision      int64 // ModRevision is the current mod revision of the key, 0 if it does not exist
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("key %s was modified: expected revision %d, found %d", e.Key, e.ExpectedRevision, e.ModRevision)
+}
+
+// PutOp returns an Op that stores value at key.
+func PutOp(key, value string) Op {
+	return Op{Key: key, Value: value}
+}
+
+// DeleteOp returns an Op that removes key. Removing a key that does not exist is not an error.
+func DeleteOp(key string) Op {
+	return Op{Key: key, Delete: true}
+}
+
+// EventType identifies the kind of change reported by an Event.
+type EventType int
+
+const (
+	// EventPut means the key was created or its value was updated.
+	EventPut EventType = iota
+	// EventDelete means the key was removed.
+	EventDelete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "PUT"
+	case EventDelete:
+		return "DELETE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // Event represents a change to a key in the storage.
+// Type tells whether the key was put or deleted
 // Key is the key that was changed
-// Value is the new value of the key
+// Value is the new value of the key; it is empty for a delete
+// PrevValue is the value of the key before the change; it is empty if the key did not exist
+// ModRevision is the storage revision at which the change happened. Revisions increase
+// monotonically across the whole storage, so a watcher can use the last one it saw to resume.
 type Event struct {
-	Key   string
-	Value string
+	Type        EventType
+	Key         string
+	Value       string
+	PrevValue   string
+	ModRevision int64
 }
 
 type Cache interface {